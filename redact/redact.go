@@ -0,0 +1,299 @@
+package redact
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync/atomic"
+)
+
+const (
+	Redacted   = "[REDACTED]"
+	DefaultTag = "redact"
+)
+
+type Configurator func(p *Policy)
+
+// Policy describes what must not reach the logs. Struct fields carrying the tag, attributes or query parameters with a
+// matching name, and headers with a matching name are replaced with Redacted. Rendered values longer than the maximum
+// size are truncated.
+type Policy struct {
+	tag        string
+	attributes map[string]struct{}
+	headers    map[string]struct{}
+	maxSize    int
+}
+
+var defaultPolicy atomic.Pointer[Policy]
+
+func init() {
+	defaultPolicy.Store(NewPolicy(
+		SetHeaders("Authorization", "Cookie", "Set-Cookie", "Proxy-Authorization"),
+		SetAttributes("password", "secret", "token", "accessToken", "refreshToken", "pin", "pic", "sessionId"),
+		SetMaxSize(4096),
+	))
+}
+
+//goland:noinspection GoUnusedExportedFunction
+func Default() *Policy {
+	return defaultPolicy.Load()
+}
+
+//goland:noinspection GoUnusedExportedFunction
+func SetDefault(p *Policy) {
+	defaultPolicy.Store(p)
+}
+
+//goland:noinspection GoUnusedExportedFunction
+func NewPolicy(configurators ...Configurator) *Policy {
+	p := &Policy{
+		tag:        DefaultTag,
+		attributes: make(map[string]struct{}),
+		headers:    make(map[string]struct{}),
+	}
+	for _, configurator := range configurators {
+		configurator(p)
+	}
+	return p
+}
+
+//goland:noinspection GoUnusedExportedFunction
+func SetTag(key string) Configurator {
+	return func(p *Policy) {
+		p.tag = key
+	}
+}
+
+//goland:noinspection GoUnusedExportedFunction
+func SetAttributes(names ...string) Configurator {
+	return func(p *Policy) {
+		for _, n := range names {
+			p.attributes[strings.ToLower(n)] = struct{}{}
+		}
+	}
+}
+
+//goland:noinspection GoUnusedExportedFunction
+func SetHeaders(names ...string) Configurator {
+	return func(p *Policy) {
+		for _, n := range names {
+			p.headers[http.CanonicalHeaderKey(n)] = struct{}{}
+		}
+	}
+}
+
+// SetMaxSize sets the maximum number of bytes logged for a single value. Zero disables truncation.
+//
+//goland:noinspection GoUnusedExportedFunction
+func SetMaxSize(size int) Configurator {
+	return func(p *Policy) {
+		p.maxSize = size
+	}
+}
+
+// Untruncated is a copy of the policy which redacts the same values without truncating, for renderings which must stay
+// complete, such as a replayable curl command.
+func (p *Policy) Untruncated() *Policy {
+	u := *p
+	u.maxSize = 0
+	return &u
+}
+
+func (p *Policy) attribute(name string) bool {
+	_, ok := p.attributes[strings.ToLower(name)]
+	return ok
+}
+
+func (p *Policy) Header(name string) bool {
+	_, ok := p.headers[http.CanonicalHeaderKey(name)]
+	return ok
+}
+
+func (p *Policy) Headers(h http.Header) http.Header {
+	r := make(http.Header, len(h))
+	for k, v := range h {
+		if p.Header(k) {
+			r[k] = []string{Redacted}
+			continue
+		}
+		r[k] = v
+	}
+	return r
+}
+
+func (p *Policy) URL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.RawQuery == "" {
+		return p.Truncate(raw)
+	}
+	q := u.Query()
+	changed := false
+	for k := range q {
+		if p.attribute(k) {
+			q[k] = []string{Redacted}
+			changed = true
+		}
+	}
+	if changed {
+		u.RawQuery = q.Encode()
+	}
+	return p.Truncate(u.String())
+}
+
+func (p *Policy) Truncate(s string) string {
+	if p.maxSize <= 0 || len(s) <= p.maxSize {
+		return s
+	}
+	return fmt.Sprintf("%s...(truncated %d bytes)", s[:p.maxSize], len(s)-p.maxSize)
+}
+
+// Value renders v as JSON for logging with redaction and truncation applied.
+func (p *Policy) Value(v interface{}) string {
+	b, err := json.Marshal(p.walk(reflect.ValueOf(v), 0, make(map[visit]struct{})))
+	if err != nil {
+		return p.Truncate(fmt.Sprintf("%+v", v))
+	}
+	return p.Truncate(string(b))
+}
+
+// Body renders a raw JSON body, such as a JSON:API document, for logging with attributes redacted by name. Bodies
+// which are not JSON are only truncated.
+func (p *Policy) Body(b []byte) string {
+	var generic interface{}
+	if err := json.Unmarshal(b, &generic); err != nil {
+		return p.Truncate(string(b))
+	}
+	return p.Value(generic)
+}
+
+var jsonMarshaler = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+const (
+	maxDepth = 32
+	cycle    = "[cycle]"
+	tooDeep  = "[max depth]"
+)
+
+type visit struct {
+	ptr uintptr
+	t   reflect.Type
+}
+
+// walk renders v for redaction. Pointers, maps and slices on the current path are tracked in visiting, so that self
+// referencing values are rendered as a cycle rather than followed indefinitely.
+func (p *Policy) walk(v reflect.Value, depth int, visiting map[visit]struct{}) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+	if depth > maxDepth {
+		return tooDeep
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		if !v.IsNil() {
+			key := visit{ptr: v.Pointer(), t: v.Type()}
+			if _, ok := visiting[key]; ok {
+				return cycle
+			}
+			visiting[key] = struct{}{}
+			defer delete(visiting, key)
+		}
+	}
+	if v.Type().Implements(jsonMarshaler) && v.Kind() != reflect.Interface {
+		if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Map || v.Kind() == reflect.Slice) && v.IsNil() {
+			return nil
+		}
+		b, err := json.Marshal(v.Interface())
+		if err != nil {
+			return fmt.Sprintf("%+v", v.Interface())
+		}
+		var generic interface{}
+		if err = json.Unmarshal(b, &generic); err != nil {
+			return string(b)
+		}
+		return p.walk(reflect.ValueOf(generic), depth+1, visiting)
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return p.walk(v.Elem(), depth+1, visiting)
+	case reflect.Struct:
+		m := make(map[string]interface{})
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name := f.Name
+			if tag, ok := f.Tag.Lookup("json"); ok {
+				tn, _, _ := strings.Cut(tag, ",")
+				if tn != "" && tn != "-" {
+					name = tn
+				}
+			}
+			if rt, ok := f.Tag.Lookup(p.tag); (ok && rt != "false" && rt != "-") || p.attribute(name) {
+				m[name] = Redacted
+				continue
+			}
+			m[name] = p.walk(v.Field(i), depth+1, visiting)
+		}
+		return m
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		m := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			k := fmt.Sprint(iter.Key().Interface())
+			if p.attribute(k) {
+				m[k] = Redacted
+				continue
+			}
+			m[k] = p.walk(iter.Value(), depth+1, visiting)
+		}
+		return m
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return fmt.Sprintf("[%d bytes]", v.Len())
+		}
+		s := make([]interface{}, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			s[i] = p.walk(v.Index(i), depth+1, visiting)
+		}
+		return s
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		return v.Type().String()
+	default:
+		return v.Interface()
+	}
+}
+
+// Deferred postpones rendering a value until the log entry is formatted, so nothing is rendered for entries below the
+// enabled log level.
+type Deferred struct {
+	p *Policy
+	v interface{}
+}
+
+func (p *Policy) Defer(v interface{}) Deferred {
+	return Deferred{p: p, v: v}
+}
+
+func (d Deferred) String() string {
+	return d.p.Value(d.v)
+}
+
+func (d Deferred) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
@@ -0,0 +1,101 @@
+package redact_test
+
+import (
+	"github.com/Chronicle20/atlas-rest/redact"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type account struct {
+	Name     string `json:"name"`
+	Password string `json:"password"`
+	Pin      string `json:"pinCode" redact:"true"`
+	Session  *session
+}
+
+type session struct {
+	SessionId string `json:"sessionId"`
+	Channel   byte   `json:"channel"`
+}
+
+func TestValue(t *testing.T) {
+	p := redact.NewPolicy(redact.SetAttributes("password", "sessionId"))
+
+	v := p.Value(account{Name: "atlas", Password: "hunter2", Pin: "1234", Session: &session{SessionId: "abc", Channel: 1}})
+	if strings.Contains(v, "hunter2") || strings.Contains(v, "1234") || strings.Contains(v, "abc") {
+		t.Fatalf("sensitive value logged [%s]", v)
+	}
+	if !strings.Contains(v, `"name":"atlas"`) || !strings.Contains(v, `"channel":1`) {
+		t.Fatalf("expected non sensitive values to be retained [%s]", v)
+	}
+
+	b := p.Body([]byte(`{"data":{"type":"accounts","id":"1","attributes":{"name":"atlas","password":"hunter2"}}}`))
+	if strings.Contains(b, "hunter2") || !strings.Contains(b, "atlas") {
+		t.Fatalf("unexpected redacted body [%s]", b)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	p := redact.NewPolicy(redact.SetMaxSize(8))
+
+	v := p.Truncate(strings.Repeat("a", 20))
+	if v != "aaaaaaaa...(truncated 12 bytes)" {
+		t.Fatalf("unexpected truncation [%s]", v)
+	}
+}
+
+func TestHeadersAndURL(t *testing.T) {
+	p := redact.NewPolicy(redact.SetHeaders("authorization"), redact.SetAttributes("token"))
+
+	h := http.Header{}
+	h.Set("Authorization", "Bearer secret")
+	h.Set("TENANT_ID", "1")
+	rh := p.Headers(h)
+	if rh.Get("Authorization") != redact.Redacted || rh.Get("TENANT_ID") != "1" {
+		t.Fatalf("unexpected redacted headers [%v]", rh)
+	}
+	if h.Get("Authorization") != "Bearer secret" {
+		t.Fatal("original headers modified")
+	}
+
+	u := p.URL("http://characters/api?name=atlas&token=secret")
+	if strings.Contains(u, "secret") || !strings.Contains(u, "name=atlas") {
+		t.Fatalf("unexpected redacted url [%s]", u)
+	}
+}
+
+type node struct {
+	Name     string                 `json:"name"`
+	Password string                 `json:"password"`
+	Next     *node                  `json:"next"`
+	Extra    map[string]interface{} `json:"extra"`
+}
+
+func TestValueCycle(t *testing.T) {
+	p := redact.NewPolicy(redact.SetAttributes("password"))
+
+	n := &node{Name: "atlas", Password: "hunter2", Extra: map[string]interface{}{}}
+	n.Next = n
+	n.Extra["self"] = n.Extra
+
+	v := p.Value(n)
+	if strings.Contains(v, "hunter2") || !strings.Contains(v, `"next":"[cycle]"`) || !strings.Contains(v, `"self":"[cycle]"`) {
+		t.Fatalf("unexpected rendering of cyclic value [%s]", v)
+	}
+
+	shared := &session{SessionId: "abc", Channel: 1}
+	v = p.Value([]*session{shared, shared})
+	if strings.Contains(v, "[cycle]") {
+		t.Fatalf("expected shared references to be rendered in full [%s]", v)
+	}
+
+	deep := &node{Name: "root"}
+	for i := 0; i < 100; i++ {
+		deep = &node{Name: "child", Next: deep}
+	}
+	v = p.Value(deep)
+	if !strings.Contains(v, "[max depth]") {
+		t.Fatalf("expected deeply nested value to be capped [%s]", v)
+	}
+}
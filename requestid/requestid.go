@@ -0,0 +1,49 @@
+package requestid
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+)
+
+type Key string
+
+const (
+	Header = "X-Request-ID"
+	ID     = Key("REQUEST_ID")
+)
+
+var ErrNotFound = errors.New("request id not found in context")
+
+//goland:noinspection GoUnusedExportedFunction
+func Generate() string {
+	return uuid.New().String()
+}
+
+// Valid reports whether an id supplied by a caller is safe to adopt. Ids must be non-empty, at most 128 characters and
+// printable ASCII without spaces.
+func Valid(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func WithContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ID, id)
+}
+
+func FromContext(ctx context.Context) func() (string, error) {
+	return func() (string, error) {
+		id, ok := ctx.Value(ID).(string)
+		if !ok || id == "" {
+			return "", ErrNotFound
+		}
+		return id, nil
+	}
+}
@@ -0,0 +1,303 @@
+package requests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrNoToken = errors.New("no token available")
+
+type Token struct {
+	Value  string
+	Expiry time.Time
+}
+
+func (t Token) Valid() bool {
+	return t.Value != "" && (t.Expiry.IsZero() || time.Now().Before(t.Expiry))
+}
+
+type TokenSource interface {
+	Token(ctx context.Context) (Token, error)
+}
+
+// RefreshableTokenSource is implemented by token sources which cache tokens, allowing a token rejected by the server
+// to be discarded.
+type RefreshableTokenSource interface {
+	TokenSource
+	Invalidate()
+}
+
+type staticTokenSource struct {
+	token Token
+}
+
+//goland:noinspection GoUnusedExportedFunction
+func StaticTokenSource(token string) TokenSource {
+	return staticTokenSource{token: Token{Value: token}}
+}
+
+func (s staticTokenSource) Token(_ context.Context) (Token, error) {
+	return s.token, nil
+}
+
+type fileTokenSource struct {
+	path    string
+	mu      sync.Mutex
+	modTime time.Time
+	token   Token
+}
+
+// FileTokenSource reads the token from a file, such as a mounted secret, reloading it whenever the file changes.
+//
+//goland:noinspection GoUnusedExportedFunction
+func FileTokenSource(path string) TokenSource {
+	return &fileTokenSource{path: path}
+}
+
+func (s *fileTokenSource) Token(_ context.Context) (Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fi, err := os.Stat(s.path)
+	if err != nil {
+		return Token{}, err
+	}
+	if s.token.Value != "" && fi.ModTime().Equal(s.modTime) {
+		return s.token, nil
+	}
+	b, err := os.ReadFile(s.path)
+	if err != nil {
+		return Token{}, err
+	}
+	value := strings.TrimSpace(string(b))
+	if value == "" {
+		return Token{}, ErrNoToken
+	}
+	s.token = Token{Value: value}
+	s.modTime = fi.ModTime()
+	return s.token, nil
+}
+
+type clientCredentialsTokenSource struct {
+	client       *http.Client
+	tokenUrl     string
+	clientId     string
+	clientSecret string
+	scopes       []string
+}
+
+type clientCredentialsResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// ClientCredentialsTokenSource exchanges client credentials for a token against the supplied token endpoint on every
+// call. It is intended to be wrapped with NewCachedTokenSource.
+//
+//goland:noinspection GoUnusedExportedFunction
+func ClientCredentialsTokenSource(tokenUrl string, clientId string, clientSecret string, scopes ...string) TokenSource {
+	return ClientCredentialsTokenSourceWithClient(http.DefaultClient, tokenUrl, clientId, clientSecret, scopes...)
+}
+
+// ClientCredentialsTokenSourceWithClient is ClientCredentialsTokenSource calling the token endpoint through the
+// supplied client, such as one trusting a private certificate authority or presenting a client certificate.
+//
+//goland:noinspection GoUnusedExportedFunction
+func ClientCredentialsTokenSourceWithClient(client *http.Client, tokenUrl string, clientId string, clientSecret string, scopes ...string) TokenSource {
+	return clientCredentialsTokenSource{client: client, tokenUrl: tokenUrl, clientId: clientId, clientSecret: clientSecret, scopes: scopes}
+}
+
+func (s clientCredentialsTokenSource) Token(ctx context.Context) (Token, error) {
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	form.Set("client_id", s.clientId)
+	form.Set("client_secret", s.clientSecret)
+	if len(s.scopes) > 0 {
+		form.Set("scope", strings.Join(s.scopes, " "))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.tokenUrl, strings.NewReader(form.Encode()))
+	if err != nil {
+		return Token{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	r, err := s.client.Do(req)
+	if err != nil {
+		return Token{}, err
+	}
+	defer r.Body.Close()
+
+	if r.StatusCode != http.StatusOK {
+		return Token{}, fmt.Errorf("token endpoint returned status code [%d]", r.StatusCode)
+	}
+
+	var resp clientCredentialsResponse
+	err = json.NewDecoder(r.Body).Decode(&resp)
+	if err != nil {
+		return Token{}, err
+	}
+	if resp.AccessToken == "" {
+		return Token{}, ErrNoToken
+	}
+
+	t := Token{Value: resp.AccessToken}
+	if resp.ExpiresIn > 0 {
+		t.Expiry = time.Now().Add(time.Duration(resp.ExpiresIn) * time.Second)
+	}
+	return t, nil
+}
+
+// CachedTokenSource caches the token of another source until it expires. Once the token is within the refresh window of
+// its expiry, a single background refresh is started while the current token continues to be served. Callers which
+// find no valid token share a single fetch from the source, which is abandoned after the fetch timeout.
+type CachedTokenSource struct {
+	source        TokenSource
+	refreshBefore time.Duration
+	fetchTimeout  time.Duration
+
+	mu     sync.Mutex
+	token  Token
+	flight *tokenFlight
+}
+
+// tokenFlight is a fetch from the source in progress, which is complete once done is closed.
+type tokenFlight struct {
+	done  chan struct{}
+	token Token
+	err   error
+}
+
+type CachedTokenSourceConfigurator func(s *CachedTokenSource)
+
+// SetTokenFetchTimeout bounds a fetch from the source, so that a hung token endpoint fails the callers waiting on it
+// and lets the next caller start a new fetch. By default, a fetch is abandoned after 30 seconds.
+//
+//goland:noinspection GoUnusedExportedFunction
+func SetTokenFetchTimeout(timeout time.Duration) CachedTokenSourceConfigurator {
+	return func(s *CachedTokenSource) {
+		s.fetchTimeout = timeout
+	}
+}
+
+//goland:noinspection GoUnusedExportedFunction
+func NewCachedTokenSource(source TokenSource, refreshBefore time.Duration, configurators ...CachedTokenSourceConfigurator) *CachedTokenSource {
+	s := &CachedTokenSource{source: source, refreshBefore: refreshBefore, fetchTimeout: 30 * time.Second}
+	for _, configurator := range configurators {
+		configurator(s)
+	}
+	return s
+}
+
+func (s *CachedTokenSource) Token(ctx context.Context) (Token, error) {
+	s.mu.Lock()
+	t := s.token
+	if !t.Valid() {
+		f := s.refresh(ctx)
+		s.mu.Unlock()
+		select {
+		case <-f.done:
+			return f.token, f.err
+		case <-ctx.Done():
+			return Token{}, ctx.Err()
+		}
+	}
+	if !t.Expiry.IsZero() && time.Until(t.Expiry) < s.refreshBefore {
+		s.refresh(ctx)
+	}
+	s.mu.Unlock()
+	return t, nil
+}
+
+func (s *CachedTokenSource) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token = Token{}
+}
+
+// refresh starts a fetch from the source unless one is already in progress, returning the fetch. It must be called
+// with the lock held. The fetch is not bound to the cancellation of ctx, as other callers may be waiting on it, but
+// to the fetch timeout, after which it is cleared whether or not the source honours the cancellation.
+func (s *CachedTokenSource) refresh(ctx context.Context) *tokenFlight {
+	if s.flight != nil {
+		return s.flight
+	}
+	f := &tokenFlight{done: make(chan struct{})}
+	s.flight = f
+	fctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.fetchTimeout)
+	go func() {
+		t, err := s.source.Token(fctx)
+		s.settle(f, t, err)
+	}()
+	go func() {
+		defer cancel()
+		select {
+		case <-f.done:
+		case <-fctx.Done():
+			s.settle(f, Token{}, fctx.Err())
+		}
+	}()
+	return f
+}
+
+// settle completes the fetch with the first outcome, either the result of the source or the expiry of the fetch
+// timeout, and clears it so that the next caller may start another.
+func (s *CachedTokenSource) settle(f *tokenFlight, t Token, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	select {
+	case <-f.done:
+		return
+	default:
+	}
+	if err != nil {
+		f.err = err
+	} else {
+		s.token = t
+		f.token = t
+	}
+	if s.flight == f {
+		s.flight = nil
+	}
+	close(f.done)
+}
+
+//goland:noinspection GoUnusedExportedFunction
+func BearerTokenHeaderDecorator(ctx context.Context, ts TokenSource) HeaderDecorator {
+	return func(h http.Header) {
+		t, err := ts.Token(ctx)
+		if err != nil {
+			return
+		}
+		h.Set("Authorization", "Bearer "+t.Value)
+	}
+}
+
+// SetTokenSource authorizes requests with a bearer token from the supplied source. When the server responds with 401
+// and the source is refreshable, the token is invalidated and the request is issued once more with a fresh token.
+//
+//goland:noinspection GoUnusedExportedFunction
+func SetTokenSource(ts TokenSource) Configurator {
+	return func(c *configuration) {
+		c.tokenSource = ts
+	}
+}
+
+func authorize(ctx context.Context, ts TokenSource, req *http.Request) error {
+	t, err := ts.Token(ctx)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+t.Value)
+	return nil
+}
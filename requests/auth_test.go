@@ -0,0 +1,146 @@
+package requests_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Chronicle20/atlas-rest/requests"
+	"github.com/sirupsen/logrus/hooks/test"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTokenRefreshOnUnauthorized(t *testing.T) {
+	l, _ := test.NewNullLogger()
+
+	var issued atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("grant_type") != "client_credentials" || r.FormValue("client_id") != "atlas" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		n := issued.Add(1)
+		_, _ = fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"Bearer","expires_in":3600}`, n)
+	}))
+	defer ts.Close()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token-2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer s.Close()
+
+	src := requests.NewCachedTokenSource(requests.ClientCredentialsTokenSource(ts.URL, "atlas", "secret"), time.Minute)
+	_, err := requests.MakeGetRequest[character](s.URL, requests.SetTokenSource(src))(l, context.Background())
+	if !errors.Is(err, requests.ErrNotFound) {
+		t.Fatalf("expected request to succeed after refresh, got [%v]", err)
+	}
+	if issued.Load() != 2 {
+		t.Fatalf("expected two tokens to be issued, got [%d]", issued.Load())
+	}
+
+	_, err = requests.MakeGetRequest[character](s.URL, requests.SetTokenSource(src))(l, context.Background())
+	if !errors.Is(err, requests.ErrNotFound) || issued.Load() != 2 {
+		t.Fatalf("expected cached token to be reused, got [%v] after [%d] tokens", err, issued.Load())
+	}
+}
+
+type countingTokenSource struct {
+	calls atomic.Int32
+	delay time.Duration
+}
+
+func (s *countingTokenSource) Token(_ context.Context) (requests.Token, error) {
+	n := s.calls.Add(1)
+	time.Sleep(s.delay)
+	return requests.Token{Value: fmt.Sprintf("token-%d", n), Expiry: time.Now().Add(time.Hour)}, nil
+}
+
+func TestCachedTokenSourceSingleFlight(t *testing.T) {
+	src := &countingTokenSource{delay: 20 * time.Millisecond}
+	cts := requests.NewCachedTokenSource(src, time.Minute)
+
+	var wg sync.WaitGroup
+	tokens := make([]string, 10)
+	for i := range tokens {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tk, err := cts.Token(context.Background())
+			if err == nil {
+				tokens[i] = tk.Value
+			}
+		}()
+	}
+	wg.Wait()
+
+	if src.calls.Load() != 1 {
+		t.Fatalf("expected a single fetch for concurrent callers, got [%d]", src.calls.Load())
+	}
+	for _, tk := range tokens {
+		if tk != "token-1" {
+			t.Fatalf("expected all callers to share the fetched token, got [%v]", tokens)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cts.Invalidate()
+	if _, err := cts.Token(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancelled caller to stop waiting, got [%v]", err)
+	}
+	tk, err := cts.Token(context.Background())
+	if err != nil || tk.Value != "token-2" || src.calls.Load() != 2 {
+		t.Fatalf("expected fetch abandoned by cancelled caller to complete, got [%s] [%v] after [%d] fetches", tk.Value, err, src.calls.Load())
+	}
+}
+
+// hangingTokenSource ignores cancellation, hanging on its first call until released.
+type hangingTokenSource struct {
+	calls   atomic.Int32
+	release chan struct{}
+}
+
+func (s *hangingTokenSource) Token(_ context.Context) (requests.Token, error) {
+	n := s.calls.Add(1)
+	if n == 1 {
+		<-s.release
+	}
+	return requests.Token{Value: fmt.Sprintf("token-%d", n), Expiry: time.Now().Add(time.Hour)}, nil
+}
+
+func TestCachedTokenSourceFetchTimeout(t *testing.T) {
+	src := &hangingTokenSource{release: make(chan struct{})}
+	defer close(src.release)
+	cts := requests.NewCachedTokenSource(src, time.Minute, requests.SetTokenFetchTimeout(20*time.Millisecond))
+
+	if _, err := cts.Token(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected hung fetch to time out, got [%v]", err)
+	}
+	tk, err := cts.Token(context.Background())
+	if err != nil || tk.Value != "token-2" {
+		t.Fatalf("expected timed out fetch to be cleared, got [%s] [%v]", tk.Value, err)
+	}
+}
+
+func TestClientCredentialsTokenSourceWithClient(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"access_token":"token","token_type":"Bearer"}`))
+	}))
+	defer ts.Close()
+
+	if _, err := requests.ClientCredentialsTokenSource(ts.URL, "atlas", "secret").Token(context.Background()); err == nil {
+		t.Fatal("expected default client to reject the untrusted token endpoint")
+	}
+	tk, err := requests.ClientCredentialsTokenSourceWithClient(ts.Client(), ts.URL, "atlas", "secret").Token(context.Background())
+	if err != nil || tk.Value != "token" {
+		t.Fatalf("expected token through supplied client, got [%s] [%v]", tk.Value, err)
+	}
+}
@@ -0,0 +1,161 @@
+package requests
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+)
+
+var ErrBodyConsumed = errors.New("request body has already been consumed")
+
+// BodySource opens the body of a request, along with its size or -1 when unknown. It is opened once per attempt, so
+// a source which can be reopened allows the request to be retried.
+type BodySource struct {
+	open       func() (io.ReadCloser, int64, error)
+	reopenable bool
+}
+
+// NewBodySource creates a source from a function which opens a fresh copy of the body on each invocation.
+//
+//goland:noinspection GoUnusedExportedFunction
+func NewBodySource(open func() (io.ReadCloser, int64, error)) BodySource {
+	return BodySource{open: open, reopenable: true}
+}
+
+//goland:noinspection GoUnusedExportedFunction
+func BytesSource(b []byte) BodySource {
+	return NewBodySource(func() (io.ReadCloser, int64, error) {
+		return io.NopCloser(bytes.NewReader(b)), int64(len(b)), nil
+	})
+}
+
+// FileSource reopens the file at path for each attempt.
+//
+//goland:noinspection GoUnusedExportedFunction
+func FileSource(path string) BodySource {
+	return NewBodySource(func() (io.ReadCloser, int64, error) {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, 0, err
+		}
+		fi, err := f.Stat()
+		if err != nil {
+			_ = f.Close()
+			return nil, 0, err
+		}
+		return f, fi.Size(), nil
+	})
+}
+
+// ReaderSource reads the body from r, which can only be consumed once. Requests using it are not retried, and fail
+// with ErrBodyConsumed if a further attempt is required.
+//
+//goland:noinspection GoUnusedExportedFunction
+func ReaderSource(r io.Reader) BodySource {
+	var once sync.Once
+	return BodySource{open: func() (io.ReadCloser, int64, error) {
+		consumed := true
+		once.Do(func() {
+			consumed = false
+		})
+		if consumed {
+			return nil, 0, ErrBodyConsumed
+		}
+		if rc, ok := r.(io.ReadCloser); ok {
+			return rc, -1, nil
+		}
+		return io.NopCloser(r), -1, nil
+	}}
+}
+
+// SetProgress reports the bytes transferred for uploads and downloads, along with the total size or -1 when unknown.
+//
+//goland:noinspection GoUnusedExportedFunction
+func SetProgress(progress func(transferred int64, total int64)) Configurator {
+	return func(c *configuration) {
+		c.progress = progress
+	}
+}
+
+// newBodyRequest creates a request with the body opened from the source. GetBody is only set for sources which can be
+// reopened, and the copies it produces do not report progress.
+func newBodyRequest(method string, url string, source BodySource, progress func(transferred int64, total int64)) (*http.Request, error) {
+	rc, size, err := source.open()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		_ = rc.Close()
+		return nil, err
+	}
+	req.ContentLength = size
+	if size == 0 {
+		_ = rc.Close()
+		req.Body = http.NoBody
+	} else if progress != nil {
+		req.Body = &progressReader{ReadCloser: rc, total: size, progress: progress}
+	} else {
+		req.Body = rc
+	}
+	if source.reopenable {
+		req.GetBody = func() (io.ReadCloser, error) {
+			rc, _, err := source.open()
+			return rc, err
+		}
+	}
+	return req, nil
+}
+
+// closeBody releases the body of a request which will not be handed to the transport.
+func closeBody(req *http.Request) {
+	if req != nil && req.Body != nil {
+		_ = req.Body.Close()
+	}
+}
+
+type progressReader struct {
+	io.ReadCloser
+	transferred int64
+	total       int64
+	progress    func(transferred int64, total int64)
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		r.transferred += int64(n)
+		r.progress(r.transferred, r.total)
+	}
+	return n, err
+}
+
+// limitedWriter counts the bytes written, reporting progress, and fails with ErrResponseTooLarge rather than exceed
+// the limit.
+type limitedWriter struct {
+	w        io.Writer
+	written  int64
+	limit    int64
+	total    int64
+	progress func(transferred int64, total int64)
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	var err error
+	if w.limit > 0 && w.written+int64(len(p)) > w.limit {
+		p = p[:w.limit-w.written]
+		err = ErrResponseTooLarge
+	}
+	n, werr := w.w.Write(p)
+	w.written += int64(n)
+	if n > 0 && w.progress != nil {
+		w.progress(w.written, w.total)
+	}
+	if werr != nil {
+		return n, werr
+	}
+	return n, err
+}
@@ -0,0 +1,109 @@
+package requests
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"strings"
+	"time"
+)
+
+func do(l logrus.FieldLogger, ctx context.Context, c *configuration, req *http.Request) (*http.Response, error) {
+	if c.rateLimiter != nil {
+		err := c.rateLimiter.Wait(ctx)
+		if err != nil {
+			closeBody(req)
+			return nil, err
+		}
+	}
+
+	if len(c.acceptEncodings) > 0 && req.Header.Get("Accept-Encoding") == "" {
+		req.Header.Set("Accept-Encoding", strings.Join(c.acceptEncodings, ", "))
+	}
+
+	if c.tokenSource != nil {
+		err := authorize(ctx, c.tokenSource, req)
+		if err != nil {
+			closeBody(req)
+			return nil, err
+		}
+	}
+
+	r, d, err := send(l, ctx, c, req)
+	if err != nil {
+		traceResponse(l, c, d, nil)
+		return nil, err
+	}
+
+	if rts, ok := c.tokenSource.(RefreshableTokenSource); ok && r.StatusCode == http.StatusUnauthorized && (req.Body == nil || req.GetBody != nil) {
+		l.Debugf("Request to [%s] was unauthorized, refreshing token.", c.policy().URL(req.URL.String()))
+		traceResponse(l, c, d, r)
+		_ = r.Body.Close()
+		rts.Invalidate()
+
+		rr := req.Clone(ctx)
+		if req.GetBody != nil {
+			rr.Body, err = req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+		}
+		err = authorize(ctx, rts, rr)
+		if err != nil {
+			closeBody(rr)
+			return nil, err
+		}
+		r, d, err = send(l, ctx, c, rr)
+		if err != nil {
+			traceResponse(l, c, d, nil)
+			return nil, err
+		}
+	}
+
+	err = decompressResponse(r)
+	if err != nil {
+		traceResponse(l, c, d, r)
+		_ = r.Body.Close()
+		return nil, err
+	}
+	traceResponse(l, c, d, r)
+	return r, nil
+}
+
+// send issues the request through the middleware chain. When dumping is enabled, the request as sent is rendered into a
+// dump for the caller to complete with the response.
+func send(l logrus.FieldLogger, ctx context.Context, c *configuration, req *http.Request) (*http.Response, *Dump, error) {
+	var release func(latency time.Duration, err error)
+	if c.concurrencyLimiter != nil {
+		var err error
+		release, err = c.concurrencyLimiter.Acquire(ctx)
+		if err != nil {
+			closeBody(req)
+			return nil, nil, err
+		}
+	}
+
+	var d *Dump
+	terminal := func(req *http.Request) (*http.Response, error) {
+		if c.signingKey != nil {
+			err := sign(*c.signingKey, req)
+			if err != nil {
+				closeBody(req)
+				return nil, err
+			}
+		}
+		l.Debugf("Issuing [%s] request to [%s].", req.Method, c.policy().URL(req.URL.String()))
+		r, err := c.client().Do(req)
+		if c.dump {
+			d = newDump(l, c, req)
+		}
+		return r, err
+	}
+
+	start := time.Now()
+	r, err := c.chain(terminal)(req)
+	if release != nil {
+		release(time.Since(start), err)
+	}
+	return r, d, err
+}
@@ -0,0 +1,67 @@
+package requests
+
+import (
+	"encoding/json"
+	"github.com/jtumidanski/api2go/jsonapi"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Codec encodes request bodies and decodes response bodies, and determines the Content-Type and Accept headers
+// sent with the request.
+type Codec interface {
+	ContentType() string
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+type JsonApiCodec struct {
+}
+
+func (JsonApiCodec) ContentType() string {
+	return "application/json; charset=utf-8"
+}
+
+func (JsonApiCodec) Marshal(v interface{}) ([]byte, error) {
+	return jsonapi.Marshal(v)
+}
+
+func (JsonApiCodec) Unmarshal(data []byte, v interface{}) error {
+	return jsonapi.Unmarshal(data, v)
+}
+
+type JsonCodec struct {
+}
+
+func (JsonCodec) ContentType() string {
+	return "application/json; charset=utf-8"
+}
+
+func (JsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+type MsgpackCodec struct {
+}
+
+func (MsgpackCodec) ContentType() string {
+	return "application/msgpack"
+}
+
+func (MsgpackCodec) Marshal(v interface{}) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+func (MsgpackCodec) Unmarshal(data []byte, v interface{}) error {
+	return msgpack.Unmarshal(data, v)
+}
+
+//goland:noinspection GoUnusedExportedFunction
+func SetCodec(codec Codec) Configurator {
+	return func(c *configuration) {
+		c.codec = codec
+	}
+}
@@ -0,0 +1,76 @@
+package requests_test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/Chronicle20/atlas-rest/requests"
+	"github.com/sirupsen/logrus/hooks/test"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type account struct {
+	Id   uint32 `json:"id" msgpack:"id"`
+	Name string `json:"name" msgpack:"name"`
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	for _, codec := range []requests.Codec{requests.JsonCodec{}, requests.MsgpackCodec{}} {
+		b, err := codec.Marshal(account{Id: 1, Name: "atlas"})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		var a account
+		err = codec.Unmarshal(b, &a)
+		if err != nil || a.Id != 1 || a.Name != "atlas" {
+			t.Fatalf("unexpected round trip through [%s], got [%v] [%v]", codec.ContentType(), a, err)
+		}
+	}
+}
+
+func TestCodecHeaders(t *testing.T) {
+	l, _ := test.NewNullLogger()
+
+	for _, codec := range []requests.Codec{requests.JsonCodec{}, requests.MsgpackCodec{}} {
+		var received http.Header
+		var input account
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r.Header.Clone()
+			b, _ := io.ReadAll(r.Body)
+			if codec.Unmarshal(b, &input) != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			out, _ := codec.Marshal(account{Id: input.Id, Name: input.Name + "-created"})
+			w.Header().Set("Content-Type", codec.ContentType())
+			_, _ = w.Write(out)
+		}))
+
+		a, err := requests.MakePostRequest[account](s.URL, account{Id: 2, Name: "chronicle"}, requests.SetCodec(codec))(l, context.Background())
+		s.Close()
+		if err != nil || a.Id != 2 || a.Name != "chronicle-created" {
+			t.Fatalf("unexpected response through [%s], got [%v] [%v]", codec.ContentType(), a, err)
+		}
+		if received.Get("Content-Type") != codec.ContentType() || received.Get("Accept") != codec.ContentType() {
+			t.Fatalf("expected headers from codec [%s], got content type [%s] and accept [%s]", codec.ContentType(), received.Get("Content-Type"), received.Get("Accept"))
+		}
+	}
+}
+
+func TestCodecAcceptOverride(t *testing.T) {
+	l, _ := test.NewNullLogger()
+
+	var accept string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accept = r.Header.Get("Accept")
+		_ = json.NewEncoder(w).Encode(account{Id: 3})
+	}))
+	defer s.Close()
+
+	_, err := requests.MakeGetRequest[account](s.URL, requests.SetCodec(requests.JsonCodec{}), requests.SetAccept("application/vnd.atlas+json"))(l, context.Background())
+	if err != nil || accept != "application/vnd.atlas+json" {
+		t.Fatalf("expected accept override, got [%s] [%v]", accept, err)
+	}
+}
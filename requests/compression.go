@@ -0,0 +1,130 @@
+package requests
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"github.com/klauspost/compress/zstd"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const (
+	EncodingGzip = "gzip"
+	EncodingZstd = "zstd"
+)
+
+// zstd coders are pooled, and limited to a single goroutine each, as by default every instance starts workers and
+// allocates windows sized for the number of CPUs.
+var (
+	zstdEncoders = sync.Pool{New: func() interface{} {
+		zw, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+		return zw
+	}}
+	zstdDecoders = sync.Pool{New: func() interface{} {
+		zr, _ := zstd.NewReader(nil, zstd.WithDecoderConcurrency(1))
+		return zr
+	}}
+)
+
+//goland:noinspection GoUnusedExportedFunction
+func SetAcceptEncoding(encodings ...string) Configurator {
+	return func(c *configuration) {
+		c.acceptEncodings = encodings
+	}
+}
+
+// SetRequestCompression compresses request bodies of at least minSize bytes with the supplied encoding.
+//
+//goland:noinspection GoUnusedExportedFunction
+func SetRequestCompression(encoding string, minSize int) Configurator {
+	return func(c *configuration) {
+		c.requestEncoding = encoding
+		c.requestCompressionMinSize = minSize
+	}
+}
+
+func compress(encoding string, body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch encoding {
+	case EncodingGzip:
+		w = gzip.NewWriter(&buf)
+	case EncodingZstd:
+		zw := zstdEncoders.Get().(*zstd.Encoder)
+		zw.Reset(&buf)
+		defer zstdEncoders.Put(zw)
+		w = zw
+	default:
+		return nil, fmt.Errorf("unsupported content encoding [%s]", encoding)
+	}
+	_, err := w.Write(body)
+	if err != nil {
+		return nil, err
+	}
+	err = w.Close()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodingBody closes the response body and releases the decoder once, as a pooled decoder must not be returned twice.
+type decodingBody struct {
+	io.Reader
+	closers []func() error
+	once    sync.Once
+	err     error
+}
+
+func (b *decodingBody) Close() error {
+	b.once.Do(func() {
+		for _, closer := range b.closers {
+			if cerr := closer(); cerr != nil && b.err == nil {
+				b.err = cerr
+			}
+		}
+	})
+	return b.err
+}
+
+// decompressResponse replaces the body of a response which carries a Content-Encoding with a decoding reader, as the
+// transport only decodes responses transparently when it sets Accept-Encoding itself.
+func decompressResponse(r *http.Response) error {
+	encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+	switch encoding {
+	case "", "identity":
+		return nil
+	case EncodingGzip:
+		gr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			_ = r.Body.Close()
+			return err
+		}
+		r.Body = &decodingBody{Reader: gr, closers: []func() error{gr.Close, r.Body.Close}}
+	case EncodingZstd:
+		zr := zstdDecoders.Get().(*zstd.Decoder)
+		err := zr.Reset(r.Body)
+		if err != nil {
+			zstdDecoders.Put(zr)
+			_ = r.Body.Close()
+			return err
+		}
+		release := func() error {
+			_ = zr.Reset(nil)
+			zstdDecoders.Put(zr)
+			return nil
+		}
+		r.Body = &decodingBody{Reader: zr, closers: []func() error{r.Body.Close, release}}
+	default:
+		_ = r.Body.Close()
+		return fmt.Errorf("unsupported content encoding [%s]", encoding)
+	}
+	r.Header.Del("Content-Encoding")
+	r.Header.Del("Content-Length")
+	r.ContentLength = -1
+	r.Uncompressed = true
+	return nil
+}
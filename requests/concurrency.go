@@ -0,0 +1,165 @@
+package requests
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+var ErrConcurrencyLimited = errors.New("concurrency limit reached")
+
+type ConcurrencyLimiterConfigurator func(cl *ConcurrencyLimiter)
+
+// ConcurrencyLimiter bounds the number of calls in flight to a single destination service. In adaptive mode the
+// limit grows additively while calls complete within the latency threshold and shrinks multiplicatively when they
+// do not.
+type ConcurrencyLimiter struct {
+	limit            float64
+	minLimit         float64
+	maxLimit         float64
+	adaptive         bool
+	latencyThreshold time.Duration
+	backoff          float64
+	reject           bool
+
+	mu       sync.Mutex
+	inFlight int
+	waiters  []chan struct{}
+}
+
+// NewConcurrencyLimiter creates a limiter admitting limit calls at once. Limits below one would admit no calls at all, so
+// the limit and the adaptive bounds are raised to at least one, and the limit is kept within the adaptive bounds.
+//
+//goland:noinspection GoUnusedExportedFunction
+func NewConcurrencyLimiter(limit int, configurators ...ConcurrencyLimiterConfigurator) *ConcurrencyLimiter {
+	cl := &ConcurrencyLimiter{
+		limit:    float64(limit),
+		minLimit: 1,
+		maxLimit: float64(limit),
+		backoff:  0.9,
+	}
+	for _, configurator := range configurators {
+		configurator(cl)
+	}
+	cl.minLimit = math.Max(1, cl.minLimit)
+	cl.maxLimit = math.Max(cl.minLimit, cl.maxLimit)
+	cl.limit = math.Min(cl.maxLimit, math.Max(cl.minLimit, cl.limit))
+	return cl
+}
+
+//goland:noinspection GoUnusedExportedFunction
+func SetAdaptiveConcurrency(minLimit int, maxLimit int, latencyThreshold time.Duration) ConcurrencyLimiterConfigurator {
+	return func(cl *ConcurrencyLimiter) {
+		cl.adaptive = true
+		cl.minLimit = float64(minLimit)
+		cl.maxLimit = float64(maxLimit)
+		cl.latencyThreshold = latencyThreshold
+	}
+}
+
+//goland:noinspection GoUnusedExportedFunction
+func SetConcurrencyBackoff(ratio float64) ConcurrencyLimiterConfigurator {
+	return func(cl *ConcurrencyLimiter) {
+		cl.backoff = ratio
+	}
+}
+
+//goland:noinspection GoUnusedExportedFunction
+func SetConcurrencyRejectWhenFull() ConcurrencyLimiterConfigurator {
+	return func(cl *ConcurrencyLimiter) {
+		cl.reject = true
+	}
+}
+
+//goland:noinspection GoUnusedExportedFunction
+func SetConcurrencyLimiter(cl *ConcurrencyLimiter) Configurator {
+	return func(c *configuration) {
+		c.concurrencyLimiter = cl
+	}
+}
+
+func (cl *ConcurrencyLimiter) Limit() int {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	return int(cl.limit)
+}
+
+func (cl *ConcurrencyLimiter) InFlight() int {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	return cl.inFlight
+}
+
+// Acquire reserves a slot for a call, queueing until one is available or ctx is done. The returned function must be
+// invoked with the outcome of the call to release the slot, and only the first invocation has any effect. Requests
+// release their slot once the response headers arrive, so the limit bounds calls awaiting a response rather than
+// response bodies still being read.
+func (cl *ConcurrencyLimiter) Acquire(ctx context.Context) (func(latency time.Duration, err error), error) {
+	cl.mu.Lock()
+	if cl.inFlight < int(cl.limit) && len(cl.waiters) == 0 {
+		cl.inFlight++
+		cl.mu.Unlock()
+		return cl.releaser(), nil
+	}
+	if cl.reject {
+		cl.mu.Unlock()
+		return nil, ErrConcurrencyLimited
+	}
+	w := make(chan struct{})
+	cl.waiters = append(cl.waiters, w)
+	cl.mu.Unlock()
+
+	select {
+	case <-w:
+		return cl.releaser(), nil
+	case <-ctx.Done():
+		cl.mu.Lock()
+		defer cl.mu.Unlock()
+		for i, o := range cl.waiters {
+			if o == w {
+				cl.waiters = append(cl.waiters[:i], cl.waiters[i+1:]...)
+				return nil, errors.Join(ErrConcurrencyLimited, ctx.Err())
+			}
+		}
+		// slot was granted concurrently with cancellation, hand it back.
+		cl.inFlight--
+		cl.dispatch()
+		return nil, errors.Join(ErrConcurrencyLimited, ctx.Err())
+	}
+}
+
+func (cl *ConcurrencyLimiter) releaser() func(latency time.Duration, err error) {
+	var once sync.Once
+	return func(latency time.Duration, err error) {
+		once.Do(func() {
+			cl.release(latency, err)
+		})
+	}
+}
+
+// release frees the slot and, in adaptive mode, adjusts the limit. Calls abandoned by the caller, such as hedges which
+// lost to another response, say nothing about the health of the service and leave the limit unchanged.
+func (cl *ConcurrencyLimiter) release(latency time.Duration, err error) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	cl.inFlight--
+	if cl.adaptive && !errors.Is(err, context.Canceled) {
+		if err != nil || latency > cl.latencyThreshold {
+			cl.limit = math.Max(cl.minLimit, cl.limit*cl.backoff)
+		} else {
+			cl.limit = math.Min(cl.maxLimit, cl.limit+1/cl.limit)
+		}
+	}
+	cl.dispatch()
+}
+
+func (cl *ConcurrencyLimiter) dispatch() {
+	for len(cl.waiters) > 0 && cl.inFlight < int(cl.limit) {
+		w := cl.waiters[0]
+		cl.waiters = cl.waiters[1:]
+		cl.inFlight++
+		close(w)
+	}
+}
@@ -0,0 +1,126 @@
+package requests_test
+
+import (
+	"context"
+	"errors"
+	"github.com/Chronicle20/atlas-rest/requests"
+	"testing"
+	"time"
+)
+
+func TestConcurrencyLimiterReject(t *testing.T) {
+	cl := requests.NewConcurrencyLimiter(1, requests.SetConcurrencyRejectWhenFull())
+
+	release, err := cl.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	_, err = cl.Acquire(context.Background())
+	if !errors.Is(err, requests.ErrConcurrencyLimited) {
+		t.Fatalf("expected concurrency limited, got [%v]", err)
+	}
+	release(time.Millisecond, nil)
+	if cl.InFlight() != 0 {
+		t.Fatalf("expected no calls in flight, got [%d]", cl.InFlight())
+	}
+}
+
+func TestConcurrencyLimiterQueue(t *testing.T) {
+	cl := requests.NewConcurrencyLimiter(1)
+
+	release, err := cl.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = cl.Acquire(ctx)
+	if !errors.Is(err, requests.ErrConcurrencyLimited) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded while queued, got [%v]", err)
+	}
+
+	acquired := make(chan struct{})
+	go func() {
+		r, err := cl.Acquire(context.Background())
+		if err == nil {
+			r(time.Millisecond, nil)
+		}
+		close(acquired)
+	}()
+	release(time.Millisecond, nil)
+
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("queued call was not granted a slot")
+	}
+}
+
+func TestAdaptiveConcurrencyLimiter(t *testing.T) {
+	cl := requests.NewConcurrencyLimiter(10, requests.SetAdaptiveConcurrency(2, 20, 100*time.Millisecond), requests.SetConcurrencyBackoff(0.5))
+
+	for i := 0; i < 3; i++ {
+		release, err := cl.Acquire(context.Background())
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		release(time.Second, nil)
+	}
+	if cl.Limit() != 2 {
+		t.Fatalf("expected limit to shrink to minimum, got [%d]", cl.Limit())
+	}
+
+	for i := 0; i < 10; i++ {
+		release, err := cl.Acquire(context.Background())
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		release(time.Millisecond, nil)
+	}
+	if cl.Limit() <= 2 {
+		t.Fatalf("expected limit to grow, got [%d]", cl.Limit())
+	}
+}
+
+func TestConcurrencyLimiterRelease(t *testing.T) {
+	cl := requests.NewConcurrencyLimiter(4, requests.SetAdaptiveConcurrency(1, 4, 100*time.Millisecond), requests.SetConcurrencyBackoff(0.5))
+
+	release, err := cl.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	release(time.Millisecond, nil)
+	release(time.Millisecond, nil)
+	if cl.InFlight() != 0 {
+		t.Fatalf("expected repeated release to have no effect, got [%d] in flight", cl.InFlight())
+	}
+
+	release, err = cl.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	release(time.Second, context.Canceled)
+	if cl.Limit() != 4 || cl.InFlight() != 0 {
+		t.Fatalf("expected cancelled call to leave limit unchanged, got [%d] with [%d] in flight", cl.Limit(), cl.InFlight())
+	}
+}
+
+func TestConcurrencyLimiterClamp(t *testing.T) {
+	for _, cl := range []*requests.ConcurrencyLimiter{
+		requests.NewConcurrencyLimiter(0, requests.SetConcurrencyRejectWhenFull()),
+		requests.NewConcurrencyLimiter(-1, requests.SetConcurrencyRejectWhenFull(), requests.SetAdaptiveConcurrency(0, 0, time.Millisecond)),
+	} {
+		if cl.Limit() != 1 {
+			t.Fatalf("expected limit to be raised to one, got [%d]", cl.Limit())
+		}
+		release, err := cl.Acquire(context.Background())
+		if err != nil {
+			t.Fatalf("expected a call to be admitted, got [%v]", err)
+		}
+		release(time.Second, errors.New("failed"))
+		if cl.Limit() != 1 {
+			t.Fatalf("expected limit to stay at one after backing off, got [%d]", cl.Limit())
+		}
+	}
+}
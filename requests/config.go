@@ -1,12 +1,58 @@
 package requests
 
+import (
+	"context"
+	"crypto/tls"
+	"github.com/Chronicle20/atlas-rest/redact"
+	"github.com/Chronicle20/atlas-rest/signature"
+	"net/http"
+	"time"
+)
+
 type configuration struct {
-	retries          int
-	headerDecorators []HeaderDecorator
+	retries                   int
+	headerDecorators          []HeaderDecorator
+	hedgePolicy               *HedgePolicy
+	hedgeUrls                 []string
+	rateLimiter               *RateLimiter
+	concurrencyLimiter        *ConcurrencyLimiter
+	idempotencyKey            string
+	disableIdempotencyKey     bool
+	codec                     Codec
+	maxResponseSize           int64
+	acceptEncodings           []string
+	requestEncoding           string
+	requestCompressionMinSize int
+	tokenSource               TokenSource
+	tlsConfig                 *tls.Config
+	signingKey                *signature.Key
+	redactionPolicy           *redact.Policy
+	disableContextDecorators  bool
+	ifMatch                   string
+	middleware                []Middleware
+	dump                      bool
+	dumpSink                  DumpSink
+	retryPolicy               func(err error) bool
+	acceptType                string
+	progress                  func(transferred int64, total int64)
+	reconnectDelay            time.Duration
+	maxReconnects             int
+	eventStreamIdleTimeout    time.Duration
+	lastEventId               string
+	tenantPropagation         []TenantPropagationConfigurator
+	failOnErrorStatus         bool
 }
 
 type Configurator func(c *configuration)
 
+func newConfiguration(configurators ...Configurator) *configuration {
+	c := &configuration{retries: 1, codec: JsonApiCodec{}, acceptEncodings: []string{EncodingGzip, EncodingZstd}}
+	for _, configurator := range configurators {
+		configurator(c)
+	}
+	return c
+}
+
 //goland:noinspection GoUnusedExportedFunction
 func SetRetries(amount int) Configurator {
 	return func(c *configuration) {
@@ -27,3 +73,75 @@ func AddHeaderDecorator(hd HeaderDecorator) Configurator {
 		c.headerDecorators = append(c.headerDecorators, hd)
 	}
 }
+
+//goland:noinspection GoUnusedExportedFunction
+func SetIdempotencyKey(key string) Configurator {
+	return func(c *configuration) {
+		c.idempotencyKey = key
+	}
+}
+
+//goland:noinspection GoUnusedExportedFunction
+func DisableIdempotencyKey() Configurator {
+	return func(c *configuration) {
+		c.disableIdempotencyKey = true
+	}
+}
+
+//goland:noinspection GoUnusedExportedFunction
+func SetMaxResponseSize(bytes int64) Configurator {
+	return func(c *configuration) {
+		c.maxResponseSize = bytes
+	}
+}
+
+// SetAccept overrides the Accept header, which otherwise requests the content type of the codec.
+//
+//goland:noinspection GoUnusedExportedFunction
+func SetAccept(mediaType string) Configurator {
+	return func(c *configuration) {
+		c.acceptType = mediaType
+	}
+}
+
+func (c *configuration) accept() string {
+	if c.acceptType != "" {
+		return c.acceptType
+	}
+	return c.codec.ContentType()
+}
+
+//goland:noinspection GoUnusedExportedFunction
+func SetRedactionPolicy(p *redact.Policy) Configurator {
+	return func(c *configuration) {
+		c.redactionPolicy = p
+	}
+}
+
+func (c *configuration) policy() *redact.Policy {
+	if c.redactionPolicy == nil {
+		return redact.Default()
+	}
+	return c.redactionPolicy
+}
+
+// DisableContextDecorators stops the span, tenant and request id found in the context from being propagated
+// automatically. Explicitly added header decorators still apply.
+//
+//goland:noinspection GoUnusedExportedFunction
+func DisableContextDecorators() Configurator {
+	return func(c *configuration) {
+		c.disableContextDecorators = true
+	}
+}
+
+func (c *configuration) decorate(ctx context.Context, h http.Header) {
+	if !c.disableContextDecorators {
+		for _, hd := range ContextHeaderDecorators(ctx, c.tenantPropagation...) {
+			hd(h)
+		}
+	}
+	for _, hd := range c.headerDecorators {
+		hd(h)
+	}
+}
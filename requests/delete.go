@@ -11,10 +11,7 @@ type EmptyBodyRequest func(l logrus.FieldLogger, ctx context.Context) error
 
 func delete(l logrus.FieldLogger, ctx context.Context) func(url string, configurators ...Configurator) error {
 	return func(url string, configurators ...Configurator) error {
-		c := &configuration{retries: 1}
-		for _, configurator := range configurators {
-			configurator(c)
-		}
+		c := newConfiguration(configurators...)
 
 		var r *http.Response
 		get := func(attempt int) (bool, error) {
@@ -26,26 +23,42 @@ func delete(l logrus.FieldLogger, ctx context.Context) func(url string, configur
 				return true, err
 			}
 
-			for _, hd := range c.headerDecorators {
-				hd(req.Header)
+			req.Header.Set("Accept", c.accept())
+
+			c.decorate(ctx, req.Header)
+			if c.ifMatch != "" {
+				req.Header.Set(IfMatchHeader, c.ifMatch)
 			}
 
 			req = req.WithContext(ctx)
 
-			l.Debugf("Issuing [%s] request to [%s].", req.Method, req.URL)
-			r, err = http.DefaultClient.Do(req)
+			r, err = do(l, ctx, c, req)
+			if err == nil && c.rejectsStatus(r.StatusCode) {
+				if serr := statusError(http.MethodDelete, r.StatusCode); c.retryable(serr) {
+					_ = r.Body.Close()
+					err = serr
+				}
+			}
 			if err != nil {
-				l.WithError(err).Warnf("Failed calling [%s] on [%s], will retry.", http.MethodDelete, url)
+				if !c.retryable(err) {
+					return false, err
+				}
+				l.WithError(err).Warnf("Failed calling [%s] on [%s], will retry.", http.MethodDelete, c.policy().URL(url))
 				return true, err
 			}
 			return false, nil
 		}
 		err := retry.Try(get, c.retries)
 		if err != nil {
-			l.WithError(err).Errorf("Unable to successfully call [%s] on [%s].", http.MethodDelete, url)
+			l.WithError(err).Errorf("Unable to successfully call [%s] on [%s].", http.MethodDelete, c.policy().URL(url))
 			return err
 		}
-		l.WithFields(logrus.Fields{"method": http.MethodDelete, "status": r.Status, "path": url}).Debugf("Printing request.")
+		_ = r.Body.Close()
+		if c.rejectsStatus(r.StatusCode) {
+			l.Debugf("Unable to successfully call [%s] on [%s], returned status code [%d].", http.MethodDelete, c.policy().URL(url), r.StatusCode)
+			return statusError(http.MethodDelete, r.StatusCode)
+		}
+		l.WithFields(logrus.Fields{"method": http.MethodDelete, "status": r.Status, "path": c.policy().URL(url)}).Debugf("Printing request.")
 
 		return err
 	}
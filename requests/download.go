@@ -0,0 +1,37 @@
+package requests
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+)
+
+// MakeDownloadRequest streams the body of a GET response to w, yielding the number of bytes written. The request is
+// retried until a response is received, but not once writing has begun. When a maximum response size is configured,
+// the download fails with ErrResponseTooLarge once it is exceeded, after w has received the bytes up to the limit.
+//
+//goland:noinspection GoUnusedExportedFunction
+func MakeDownloadRequest(url string, w io.Writer, configurators ...Configurator) Request[int64] {
+	return func(l logrus.FieldLogger, ctx context.Context) (int64, error) {
+		c := newConfiguration(append([]Configurator{SetAccept("*/*")}, configurators...)...)
+
+		r, err := fetch(l, ctx, c, url)
+		if err != nil {
+			return 0, err
+		}
+		defer r.Body.Close()
+
+		if c.maxResponseSize > 0 && r.ContentLength > c.maxResponseSize {
+			return 0, ErrResponseTooLarge
+		}
+
+		lw := &limitedWriter{w: w, limit: c.maxResponseSize, total: r.ContentLength, progress: c.progress}
+		n, err := io.Copy(lw, r.Body)
+		l.WithFields(logrus.Fields{"method": http.MethodGet, "status": r.Status, "path": c.policy().URL(url), "bytes": n}).Debugf("Printing request.")
+		if err != nil {
+			return n, err
+		}
+		return n, nil
+	}
+}
@@ -0,0 +1,234 @@
+package requests
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/Chronicle20/atlas-rest/redact"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Dump is the rendering of a single outbound call, with secrets redacted according to the redaction policy.
+type Dump struct {
+	Curl     string
+	Request  string
+	Response string
+}
+
+type DumpSink func(d Dump)
+
+// EnableDump emits a Dump of every call through the logger at debug level. The response is captured, after
+// decompression, as it is read by the caller, and the dump is emitted once the response body is closed. Bodies larger
+// than 64 KiB are not rendered.
+//
+//goland:noinspection GoUnusedExportedFunction
+func EnableDump() Configurator {
+	return func(c *configuration) {
+		c.dump = true
+	}
+}
+
+// SetDumpSink emits a Dump of every call to the supplied sink rather than the logger.
+//
+//goland:noinspection GoUnusedExportedFunction
+func SetDumpSink(sink DumpSink) Configurator {
+	return func(c *configuration) {
+		c.dump = true
+		c.dumpSink = sink
+	}
+}
+
+func (c *configuration) sink(l logrus.FieldLogger) DumpSink {
+	if c.dumpSink != nil {
+		return c.dumpSink
+	}
+	return func(d Dump) {
+		l.WithFields(logrus.Fields{"curl": d.Curl, "request": d.Request, "response": d.Response}).Debugf("Dumping request.")
+	}
+}
+
+// Curl renders the request as a curl command, redacting headers, query parameters and body attributes covered by the
+// policy. The body is never truncated, as a partial body would replay a different request; a body which cannot be
+// rendered in full, because it cannot be reread, is encoded or exceeds the dump limit, is left out and the command is
+// marked with a "# body omitted" or "# body truncated" comment.
+//
+//goland:noinspection GoUnusedExportedFunction
+func Curl(req *http.Request, p *redact.Policy) (string, error) {
+	body, err := requestBody(req)
+	if err != nil {
+		return "", err
+	}
+
+	sb := strings.Builder{}
+	sb.WriteString("curl -X ")
+	sb.WriteString(req.Method)
+	sb.WriteString(" ")
+	sb.WriteString(quote(p.URL(req.URL.String())))
+	h := p.Headers(req.Header)
+	for _, k := range sortedKeys(h) {
+		for _, v := range h[k] {
+			sb.WriteString(" -H ")
+			sb.WriteString(quote(k + ": " + v))
+		}
+	}
+	encoding := req.Header.Get("Content-Encoding")
+	switch {
+	case req.Body != nil && req.Body != http.NoBody && req.GetBody == nil:
+		sb.WriteString(" # body omitted")
+	case len(body) > maxDumpBodySize:
+		sb.WriteString(" # body truncated")
+	case len(body) > 0 && encoding != "" && encoding != "identity":
+		sb.WriteString(" # body omitted")
+	case len(body) > 0:
+		sb.WriteString(" --data-binary ")
+		sb.WriteString(quote(p.Untruncated().Body(body)))
+	}
+	return sb.String(), nil
+}
+
+func dumpRequest(req *http.Request, p *redact.Policy) (string, error) {
+	body, err := requestBody(req)
+	if err != nil {
+		return "", err
+	}
+
+	sb := strings.Builder{}
+	sb.WriteString(fmt.Sprintf("%s %s %s\r\n", req.Method, p.URL(req.URL.RequestURI()), req.Proto))
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	sb.WriteString("Host: " + host + "\r\n")
+	writeHeaders(&sb, p.Headers(req.Header))
+	sb.WriteString("\r\n")
+	sb.WriteString(renderBody(p, req.Header, body, false))
+	return sb.String(), nil
+}
+
+// dumpResponse renders the response with the body captured from it, which is omitted when it exceeded the limit.
+func dumpResponse(r *http.Response, p *redact.Policy, body []byte, exceeded bool) string {
+	sb := strings.Builder{}
+	sb.WriteString(fmt.Sprintf("%s %s\r\n", r.Proto, r.Status))
+	writeHeaders(&sb, p.Headers(r.Header))
+	sb.WriteString("\r\n")
+	sb.WriteString(renderBody(p, r.Header, body, exceeded))
+	return sb.String()
+}
+
+// newDump renders the request as sent, returning the dump to be completed with the response.
+func newDump(l logrus.FieldLogger, c *configuration, req *http.Request) *Dump {
+	p := c.policy()
+	d := &Dump{}
+	var err error
+	d.Curl, err = Curl(req, p)
+	if err != nil {
+		l.WithError(err).Warnf("Unable to render request to [%s] for dump.", p.URL(req.URL.String()))
+	}
+	d.Request, err = dumpRequest(req, p)
+	if err != nil {
+		l.WithError(err).Warnf("Unable to render request to [%s] for dump.", p.URL(req.URL.String()))
+	}
+	return d
+}
+
+// traceResponse completes the dump with the response. The body is captured as it is read, and the dump emitted once
+// it is closed, so that streamed responses are neither buffered nor delayed. Without a response, the dump is emitted
+// immediately.
+func traceResponse(l logrus.FieldLogger, c *configuration, d *Dump, r *http.Response) {
+	if d == nil {
+		return
+	}
+	if r == nil {
+		c.sink(l)(*d)
+		return
+	}
+	r.Body = &dumpBody{ReadCloser: r.Body, emit: func(body []byte, exceeded bool) {
+		d.Response = dumpResponse(r, c.policy(), body, exceeded)
+		c.sink(l)(*d)
+	}}
+}
+
+const maxDumpBodySize = 64 << 10
+
+type dumpBody struct {
+	io.ReadCloser
+	captured bytes.Buffer
+	exceeded bool
+	emit     func(body []byte, exceeded bool)
+	once     sync.Once
+}
+
+func (b *dumpBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 && !b.exceeded {
+		if b.captured.Len()+n > maxDumpBodySize {
+			b.exceeded = true
+			b.captured = bytes.Buffer{}
+		} else {
+			b.captured.Write(p[:n])
+		}
+	}
+	return n, err
+}
+
+func (b *dumpBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() {
+		b.emit(b.captured.Bytes(), b.exceeded)
+	})
+	return err
+}
+
+func requestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody == nil {
+		return nil, nil
+	}
+	rc, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(io.LimitReader(rc, maxDumpBodySize+1))
+}
+
+// renderBody redacts the body. A body which exceeded the limit cannot be parsed to be redacted, so is not rendered.
+func renderBody(p *redact.Policy, h http.Header, body []byte, exceeded bool) string {
+	if exceeded || len(body) > maxDumpBodySize {
+		return fmt.Sprintf("<body exceeds %d bytes>", maxDumpBodySize)
+	}
+	if len(body) == 0 {
+		return ""
+	}
+	if encoding := h.Get("Content-Encoding"); encoding != "" && encoding != "identity" {
+		return fmt.Sprintf("<%d bytes %s encoded>", len(body), encoding)
+	}
+	return p.Body(body)
+}
+
+func writeHeaders(sb *strings.Builder, h http.Header) {
+	for _, k := range sortedKeys(h) {
+		for _, v := range h[k] {
+			sb.WriteString(k + ": " + v + "\r\n")
+		}
+	}
+}
+
+func sortedKeys(h http.Header) []string {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,142 @@
+package requests_test
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"github.com/Chronicle20/atlas-rest/redact"
+	"github.com/Chronicle20/atlas-rest/requests"
+	"github.com/sirupsen/logrus/hooks/test"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type credentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+func TestDump(t *testing.T) {
+	l, _ := test.NewNullLogger()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"token":"abc","username":"atlas"}`))
+	}))
+	defer s.Close()
+
+	var dumps []requests.Dump
+	_, err := requests.MakePostRequest[credentials](s.URL+"/login?secret=xyz", credentials{Username: "atlas", Password: "hunter2"},
+		requests.SetCodec(requests.JsonCodec{}),
+		requests.SetTokenSource(requests.StaticTokenSource("bearer-secret")),
+		requests.SetDumpSink(func(d requests.Dump) { dumps = append(dumps, d) }),
+	)(l, context.Background())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(dumps) != 1 {
+		t.Fatalf("expected a single dump, got [%d]", len(dumps))
+	}
+
+	d := dumps[0]
+	if !strings.HasPrefix(d.Curl, "curl -X POST '"+s.URL+"/login?secret=") || !strings.Contains(d.Curl, "--data-binary") {
+		t.Fatalf("unexpected curl [%s]", d.Curl)
+	}
+	for _, secret := range []string{"hunter2", "bearer-secret", "xyz"} {
+		if strings.Contains(d.Curl, secret) || strings.Contains(d.Request, secret) {
+			t.Fatalf("secret [%s] was not redacted:\n%s\n%s", secret, d.Curl, d.Request)
+		}
+	}
+	if !strings.Contains(d.Request, "atlas") || !strings.HasPrefix(d.Response, "HTTP/1.1 200 OK") || strings.Contains(d.Response, "abc") {
+		t.Fatalf("unexpected dump:\n%s\n%s", d.Request, d.Response)
+	}
+}
+
+func TestDumpAfterDecompression(t *testing.T) {
+	l, _ := test.NewNullLogger()
+
+	large := `{"name":"` + strings.Repeat("a", 70000) + `"}`
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := `{"name":"atlas"}`
+		if r.URL.Path == "/large" {
+			body = large
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Encoding", "gzip")
+		gw := gzip.NewWriter(w)
+		_, _ = gw.Write([]byte(body))
+		_ = gw.Close()
+	}))
+	defer s.Close()
+
+	var dumps []requests.Dump
+	sink := requests.SetDumpSink(func(d requests.Dump) { dumps = append(dumps, d) })
+
+	_, err := requests.MakeGetRequest[account](s.URL, requests.SetCodec(requests.JsonCodec{}), sink)(l, context.Background())
+	if err != nil || len(dumps) != 1 || !strings.Contains(dumps[0].Response, `"name":"atlas"`) {
+		t.Fatalf("expected decompressed response in dump, got [%v] [%v]", dumps, err)
+	}
+
+	dumps = nil
+	a, err := requests.MakeGetRequest[account](s.URL+"/large", requests.SetCodec(requests.JsonCodec{}), sink)(l, context.Background())
+	if err != nil || len(a.Name) != 70000 || len(dumps) != 1 || !strings.Contains(dumps[0].Response, "<body exceeds") {
+		t.Fatalf("expected large body to be omitted from dump, got [%v]", err)
+	}
+}
+
+func TestDumpDoesNotBlockStreams(t *testing.T) {
+	l, _ := test.NewNullLogger()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: atlas\n\n"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer s.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	var dumps []requests.Dump
+	stop := errors.New("stop")
+	err := requests.MakeEventStreamRequest(s.URL, func(e requests.Event) error {
+		return stop
+	}, requests.SetDumpSink(func(d requests.Dump) { dumps = append(dumps, d) }))(l, ctx)
+	if !errors.Is(err, stop) {
+		t.Fatalf("expected event to be received while streaming, got [%v]", err)
+	}
+	if len(dumps) != 1 || !strings.Contains(dumps[0].Response, "data: atlas") {
+		t.Fatalf("expected dump of consumed stream, got [%v]", dumps)
+	}
+}
+
+func TestCurlBody(t *testing.T) {
+	p := redact.NewPolicy(redact.SetAttributes("password"), redact.SetMaxSize(16))
+
+	long := `{"username":"` + strings.Repeat("a", 64) + `","password":"hunter2"}`
+	req, _ := http.NewRequest(http.MethodPost, "http://atlas/login", strings.NewReader(long))
+	curl, err := requests.Curl(req, p)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if !strings.Contains(curl, strings.Repeat("a", 64)) || strings.Contains(curl, "hunter2") || strings.Contains(curl, "#") {
+		t.Fatalf("expected complete redacted body, got [%s]", curl)
+	}
+
+	req, _ = http.NewRequest(http.MethodPost, "http://atlas/login", io.NopCloser(strings.NewReader(long)))
+	curl, _ = requests.Curl(req, p)
+	if !strings.HasSuffix(curl, " # body omitted") || strings.Contains(curl, "--data-binary") {
+		t.Fatalf("expected body which cannot be reread to be marked, got [%s]", curl)
+	}
+
+	req, _ = http.NewRequest(http.MethodPost, "http://atlas/login", strings.NewReader(strings.Repeat("a", 70000)))
+	curl, _ = requests.Curl(req, p)
+	if !strings.HasSuffix(curl, " # body truncated") || strings.Contains(curl, "--data-binary") {
+		t.Fatalf("expected oversized body to be marked, got [%s]", curl)
+	}
+}
@@ -0,0 +1,231 @@
+package requests
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// StatusError is returned when a service responds with a status code which does not map to a more specific error.
+type StatusError struct {
+	Method     string
+	StatusCode int
+}
+
+func (e StatusError) Error() string {
+	return fmt.Sprintf("unknown error: [%s] returned status code [%d]", e.Method, e.StatusCode)
+}
+
+var ErrIdempotencyConflict = errors.New("idempotency key conflict")
+
+// IdempotencyError is returned when the service rejects the idempotency key of a request, either with 409 because a
+// request with the key is still in progress, or with 422 because the key was used with a different payload. Only the
+// former is retryable, after the delay supplied by the service.
+type IdempotencyError struct {
+	Method     string
+	StatusCode int
+	Delay      time.Duration
+}
+
+func (e IdempotencyError) Error() string {
+	return fmt.Sprintf("%s: [%s] returned status code [%d]", ErrIdempotencyConflict, e.Method, e.StatusCode)
+}
+
+func (e IdempotencyError) Is(target error) bool {
+	return target == ErrIdempotencyConflict
+}
+
+func (e IdempotencyError) Unwrap() error {
+	return StatusError{Method: e.Method, StatusCode: e.StatusCode}
+}
+
+func (e IdempotencyError) RetryAfter() time.Duration {
+	return e.Delay
+}
+
+// idempotencyError recognises a rejection of the idempotency key, which the service marks by echoing the key, so that
+// it fails the request regardless of whether other error statuses do.
+func idempotencyError(method string, r *http.Response) error {
+	if r.StatusCode != http.StatusConflict && r.StatusCode != http.StatusUnprocessableEntity {
+		return nil
+	}
+	if r.Header.Get(IdempotencyKeyHeader) == "" {
+		return nil
+	}
+	return IdempotencyError{Method: method, StatusCode: r.StatusCode, Delay: retryAfter(r.Header.Get("Retry-After"))}
+}
+
+// retryAfter parses a Retry-After value given in seconds or as an HTTP date.
+func retryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if s, err := strconv.Atoi(v); err == nil && s >= 0 {
+		return time.Duration(s) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
+// statusError maps the status code of an unsuccessful response to an error.
+func statusError(method string, code int) error {
+	switch code {
+	case http.StatusBadRequest:
+		return ErrBadRequest
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusPreconditionFailed:
+		return ErrPreconditionFailed
+	}
+	return StatusError{Method: method, StatusCode: code}
+}
+
+func statusCode(err error) (int, bool) {
+	var se StatusError
+	if errors.As(err, &se) {
+		return se.StatusCode, true
+	}
+	return 0, false
+}
+
+// IsTimeout reports whether the call timed out, either in the transport, by deadline, or as reported by the service.
+//
+//goland:noinspection GoUnusedExportedFunction
+func IsTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return true
+	}
+	code, ok := statusCode(err)
+	return ok && (code == http.StatusRequestTimeout || code == http.StatusGatewayTimeout)
+}
+
+// IsCanceled reports whether the call was abandoned because its context was cancelled.
+//
+//goland:noinspection GoUnusedExportedFunction
+func IsCanceled(err error) bool {
+	return errors.Is(err, context.Canceled)
+}
+
+// IsThrottled reports whether the call was rejected locally by a rate or concurrency limiter, or remotely with 429.
+//
+//goland:noinspection GoUnusedExportedFunction
+func IsThrottled(err error) bool {
+	if errors.Is(err, ErrRateLimited) || errors.Is(err, ErrConcurrencyLimited) {
+		return true
+	}
+	code, ok := statusCode(err)
+	return ok && code == http.StatusTooManyRequests
+}
+
+// IsClientError reports whether the service rejected the request as invalid, in which case issuing it again unchanged
+// will not succeed.
+//
+//goland:noinspection GoUnusedExportedFunction
+func IsClientError(err error) bool {
+	if errors.Is(err, ErrBadRequest) || errors.Is(err, ErrNotFound) || errors.Is(err, ErrPreconditionFailed) {
+		return true
+	}
+	code, ok := statusCode(err)
+	return ok && code >= 400 && code < 500
+}
+
+//goland:noinspection GoUnusedExportedFunction
+func IsServerError(err error) bool {
+	code, ok := statusCode(err)
+	return ok && code >= 500
+}
+
+// IsRetryable reports whether issuing the request again may succeed. Only failures known to be transient are
+// retryable: dropped, refused or reset connections, transport timeouts, DNS failures, server errors, 408 or 429
+// responses and idempotency keys still in progress. Anything else, including cancellation, expired deadlines, local
+// limiter rejections, client errors, idempotency keys reused with a different payload, certificate failures, missing
+// tokens, responses which could not be decoded and bodies which cannot be reread, is not.
+//
+//goland:noinspection GoUnusedExportedFunction
+func IsRetryable(err error) bool {
+	if err == nil || IsCanceled(err) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var ie IdempotencyError
+	if errors.As(err, &ie) {
+		return ie.StatusCode == http.StatusConflict
+	}
+	if code, ok := statusCode(err); ok {
+		return code >= 500 || code == http.StatusRequestTimeout || code == http.StatusTooManyRequests
+	}
+	if isCertificateError(err) {
+		return false
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNABORTED) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	var oe *net.OpError
+	if errors.As(err, &oe) {
+		return true
+	}
+	var de *net.DNSError
+	if errors.As(err, &de) {
+		return true
+	}
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
+
+func isCertificateError(err error) bool {
+	var cve *tls.CertificateVerificationError
+	var uae x509.UnknownAuthorityError
+	var cie x509.CertificateInvalidError
+	var he x509.HostnameError
+	return errors.As(err, &cve) || errors.As(err, &uae) || errors.As(err, &cie) || errors.As(err, &he)
+}
+
+// FailOnErrorStatus fails DELETE, PATCH, POST and PUT requests which receive a status outside 2xx with the error it
+// maps to, retrying those accepted by the retry policy. By default, only 412 fails such a request, and any other
+// status is treated as success, with a body decoded as the result.
+//
+//goland:noinspection GoUnusedExportedFunction
+func FailOnErrorStatus() Configurator {
+	return func(c *configuration) {
+		c.failOnErrorStatus = true
+	}
+}
+
+// rejectsStatus reports whether a DELETE, PATCH, POST or PUT request fails with the status code.
+func (c *configuration) rejectsStatus(code int) bool {
+	if code >= 200 && code < 300 {
+		return false
+	}
+	return c.failOnErrorStatus || code == http.StatusPreconditionFailed
+}
+
+// SetRetryPolicy determines which failures are retried, up to the configured number of retries. By default,
+// IsRetryable is used.
+//
+//goland:noinspection GoUnusedExportedFunction
+func SetRetryPolicy(retryable func(err error) bool) Configurator {
+	return func(c *configuration) {
+		c.retryPolicy = retryable
+	}
+}
+
+func (c *configuration) retryable(err error) bool {
+	if c.retryPolicy != nil {
+		return c.retryPolicy(err)
+	}
+	return IsRetryable(err)
+}
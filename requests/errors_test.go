@@ -0,0 +1,141 @@
+package requests_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/Chronicle20/atlas-rest/requests"
+	"github.com/sirupsen/logrus/hooks/test"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"syscall"
+	"testing"
+)
+
+func TestErrorClassification(t *testing.T) {
+	l, _ := test.NewNullLogger()
+
+	status := http.StatusServiceUnavailable
+	calls := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(status)
+	}))
+	defer s.Close()
+
+	_, err := requests.MakeGetRequest[character](s.URL)(l, context.Background())
+	if !requests.IsServerError(err) || !requests.IsRetryable(err) || requests.IsClientError(err) {
+		t.Fatalf("expected retryable server error, got [%v]", err)
+	}
+
+	status = http.StatusConflict
+	err = requests.MakeDeleteRequest(s.URL, requests.FailOnErrorStatus())(l, context.Background())
+	if !requests.IsClientError(err) || requests.IsRetryable(err) {
+		t.Fatalf("expected client error, got [%v]", err)
+	}
+
+	status = http.StatusNotFound
+	_, err = requests.MakePostRequest[character](s.URL, character{Id: "1"}, requests.FailOnErrorStatus())(l, context.Background())
+	if !errors.Is(err, requests.ErrNotFound) || !requests.IsClientError(err) {
+		t.Fatalf("expected not found, got [%v]", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = requests.MakeGetRequest[character](s.URL)(l, ctx)
+	if !requests.IsCanceled(err) || requests.IsRetryable(err) {
+		t.Fatalf("expected cancellation, got [%v]", err)
+	}
+
+	if !requests.IsThrottled(fmt.Errorf("wrapped: %w", requests.ErrRateLimited)) || requests.IsRetryable(requests.ErrConcurrencyLimited) {
+		t.Fatal("expected limiter rejections to be throttled and not retryable")
+	}
+
+	status = http.StatusTooManyRequests
+	calls = 0
+	_, err = requests.MakeGetRequest[character](s.URL, requests.SetRetries(3), requests.SetRetryPolicy(func(err error) bool { return false }))(l, context.Background())
+	if !requests.IsThrottled(err) || calls != 1 {
+		t.Fatalf("expected retry policy to prevent retries, got [%v] after [%d] calls", err, calls)
+	}
+}
+
+func TestErrorStatusOfMutatingRequests(t *testing.T) {
+	l, _ := test.NewNullLogger()
+
+	status := http.StatusConflict
+	calls := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(status)
+	}))
+	defer s.Close()
+
+	err := requests.MakeDeleteRequest(s.URL)(l, context.Background())
+	if err != nil {
+		t.Fatalf("expected status to be ignored by default, got [%v]", err)
+	}
+	_, err = requests.MakePutRequest[character](s.URL, character{Id: "1"})(l, context.Background())
+	if err != nil {
+		t.Fatalf("expected status to be ignored by default, got [%v]", err)
+	}
+
+	status = http.StatusPreconditionFailed
+	err = requests.MakeDeleteRequest(s.URL)(l, context.Background())
+	if !errors.Is(err, requests.ErrPreconditionFailed) {
+		t.Fatalf("expected precondition failed by default, got [%v]", err)
+	}
+
+	status = http.StatusServiceUnavailable
+	calls = 0
+	_, err = requests.MakePostRequest[character](s.URL, character{Id: "1"}, requests.SetRetries(3))(l, context.Background())
+	if err != nil || calls != 1 {
+		t.Fatalf("expected no retries by default, got [%v] after [%d] calls", err, calls)
+	}
+
+	calls = 0
+	_, err = requests.MakePostRequest[character](s.URL, character{Id: "1"}, requests.SetRetries(3), requests.FailOnErrorStatus())(l, context.Background())
+	if !requests.IsServerError(err) || calls != 3 {
+		t.Fatalf("expected retried server error, got [%v] after [%d] calls", err, calls)
+	}
+}
+
+func TestRetryableClassification(t *testing.T) {
+	l, _ := test.NewNullLogger()
+
+	calls := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		_, _ = w.Write([]byte("not a document"))
+	}))
+	defer s.Close()
+
+	_, err := requests.MakeGetRequest[character](s.URL, requests.SetRetries(3))(l, context.Background())
+	if !errors.Is(err, requests.ErrMalformedResponse) || requests.IsRetryable(err) || calls != 1 {
+		t.Fatalf("expected malformed response which is not retried, got [%v] after [%d] calls", err, calls)
+	}
+
+	for _, err := range []error{
+		requests.ErrNoToken,
+		errors.New("unknown"),
+		&url.Error{Op: "Get", URL: "https://localhost", Err: x509.UnknownAuthorityError{}},
+		&url.Error{Op: "Get", URL: "https://localhost", Err: &tls.CertificateVerificationError{Err: x509.HostnameError{}}},
+	} {
+		if requests.IsRetryable(err) {
+			t.Fatalf("expected [%v] not to be retryable", err)
+		}
+	}
+	for _, err := range []error{
+		&url.Error{Op: "Get", URL: "http://localhost", Err: io.ErrUnexpectedEOF},
+		&url.Error{Op: "Get", URL: "http://localhost", Err: &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}},
+		fmt.Errorf("wrapped: %w", syscall.ECONNRESET),
+	} {
+		if !requests.IsRetryable(err) {
+			t.Fatalf("expected [%v] to be retryable", err)
+		}
+	}
+}
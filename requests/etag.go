@@ -0,0 +1,53 @@
+package requests
+
+import (
+	"context"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"net/http"
+)
+
+const (
+	ETagHeader    = "ETag"
+	IfMatchHeader = "If-Match"
+)
+
+var ErrPreconditionFailed = errors.New("precondition failed")
+
+// Versioned is a resource paired with the entity tag of the version it was read at.
+type Versioned[A any] struct {
+	Value A
+	ETag  string
+}
+
+// MakeVersionedGetRequest issues a GET request like MakeGetRequest, also surfacing the ETag returned by the server so
+// it may be supplied to a later PATCH or PUT with SetIfMatch.
+//
+//goland:noinspection GoUnusedExportedFunction
+func MakeVersionedGetRequest[A any](url string, configurators ...Configurator) Request[Versioned[A]] {
+	return func(l logrus.FieldLogger, ctx context.Context) (Versioned[A], error) {
+		c := newConfiguration(configurators...)
+
+		r, err := fetch(l, ctx, c, url)
+		if err != nil {
+			return Versioned[A]{}, err
+		}
+		etag := r.Header.Get(ETagHeader)
+		resp, err := processResponse[A](c, r)
+		l.WithFields(logrus.Fields{"method": http.MethodGet, "status": r.Status, "path": c.policy().URL(url), "etag": etag, "response": c.policy().Defer(resp)}).Debugf("Printing request.")
+		if err != nil {
+			return Versioned[A]{}, err
+		}
+		return Versioned[A]{Value: resp, ETag: etag}, nil
+	}
+}
+
+// SetIfMatch makes a PATCH, PUT or DELETE conditional on the resource still being at the supplied version. When it is
+// not, the request fails with ErrPreconditionFailed.
+//
+//goland:noinspection GoUnusedExportedFunction
+func SetIfMatch(etag string) Configurator {
+	return func(c *configuration) {
+		c.ifMatch = etag
+	}
+}
@@ -0,0 +1,116 @@
+package requests
+
+import (
+	"context"
+	"errors"
+	"github.com/Chronicle20/atlas-model/model"
+	"github.com/sirupsen/logrus"
+)
+
+var ErrNoRequests = errors.New("no requests supplied")
+
+func matches(err error, targets []error) bool {
+	if len(targets) == 0 {
+		return true
+	}
+	for _, target := range targets {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultOnNotFound yields the supplied value when the request fails with ErrNotFound.
+//
+//goland:noinspection GoUnusedExportedFunction
+func DefaultOnNotFound[A any](r Request[A], value A) Request[A] {
+	return Fallback(r, func(l logrus.FieldLogger, ctx context.Context) (A, error) {
+		return value, nil
+	}, ErrNotFound)
+}
+
+// Fallback issues the alternate request when the primary fails with one of the supplied errors, or with any error
+// when none are supplied.
+//
+//goland:noinspection GoUnusedExportedFunction
+func Fallback[A any](r Request[A], alternate Request[A], errs ...error) Request[A] {
+	return func(l logrus.FieldLogger, ctx context.Context) (A, error) {
+		result, err := r(l, ctx)
+		if err == nil || !matches(err, errs) || ctx.Err() != nil {
+			return result, err
+		}
+		l.WithError(err).Debugf("Request failed, falling back to alternate.")
+		return alternate(l, ctx)
+	}
+}
+
+// FirstSuccessful issues the requests in order, yielding the result of the first to succeed. When all fail, the
+// joined errors are returned.
+//
+//goland:noinspection GoUnusedExportedFunction
+func FirstSuccessful[A any](rs ...Request[A]) Request[A] {
+	return func(l logrus.FieldLogger, ctx context.Context) (A, error) {
+		var result A
+		if len(rs) == 0 {
+			return result, ErrNoRequests
+		}
+		errs := make([]error, 0, len(rs))
+		for _, r := range rs {
+			var err error
+			result, err = r(l, ctx)
+			if err == nil {
+				return result, nil
+			}
+			errs = append(errs, err)
+			if ctx.Err() != nil {
+				break
+			}
+		}
+		var zero A
+		return zero, errors.Join(errs...)
+	}
+}
+
+// DefaultProviderOnNotFound yields the supplied value when the provider fails with ErrNotFound.
+//
+//goland:noinspection GoUnusedExportedFunction
+func DefaultProviderOnNotFound[M any](p model.Provider[M], value M) model.Provider[M] {
+	return FallbackProvider(p, model.FixedProvider(value), ErrNotFound)
+}
+
+// FallbackProvider invokes the alternate provider when the primary fails with one of the supplied errors, or with any
+// error when none are supplied.
+//
+//goland:noinspection GoUnusedExportedFunction
+func FallbackProvider[M any](p model.Provider[M], alternate model.Provider[M], errs ...error) model.Provider[M] {
+	return func() (M, error) {
+		result, err := p()
+		if err == nil || !matches(err, errs) {
+			return result, err
+		}
+		return alternate()
+	}
+}
+
+// FirstSuccessfulProvider invokes the providers in order, yielding the result of the first to succeed. When all
+// fail, the joined errors are returned.
+//
+//goland:noinspection GoUnusedExportedFunction
+func FirstSuccessfulProvider[M any](ps ...model.Provider[M]) model.Provider[M] {
+	return func() (M, error) {
+		var zero M
+		if len(ps) == 0 {
+			return zero, ErrNoRequests
+		}
+		errs := make([]error, 0, len(ps))
+		for _, p := range ps {
+			result, err := p()
+			if err == nil {
+				return result, nil
+			}
+			errs = append(errs, err)
+		}
+		return zero, errors.Join(errs...)
+	}
+}
@@ -0,0 +1,112 @@
+package requests
+
+import (
+	"context"
+	"errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Future is the eventual result of a Request started with Async.
+type Future[A any] struct {
+	done   chan struct{}
+	cancel context.CancelFunc
+	result A
+	err    error
+}
+
+// Async starts the request in the background and returns a Future for its result. The request observes the
+// cancellation of ctx as well as that of the Future.
+//
+//goland:noinspection GoUnusedExportedFunction
+func Async[A any](l logrus.FieldLogger, ctx context.Context, r Request[A]) *Future[A] {
+	fctx, cancel := context.WithCancel(ctx)
+	f := &Future[A]{done: make(chan struct{}), cancel: cancel}
+	go func() {
+		defer close(f.done)
+		defer cancel()
+		f.result, f.err = r(l, fctx)
+	}()
+	return f
+}
+
+// Done is closed once the request has completed.
+func (f *Future[A]) Done() <-chan struct{} {
+	return f.done
+}
+
+// Cancel cancels the context of the request. The Future still completes, generally with a context error.
+func (f *Future[A]) Cancel() {
+	f.cancel()
+}
+
+// Await blocks until the request completes or ctx is done. Abandoning the wait does not cancel the request.
+func (f *Future[A]) Await(ctx context.Context) (A, error) {
+	select {
+	case <-f.done:
+		return f.result, f.err
+	case <-ctx.Done():
+		var zero A
+		return zero, ctx.Err()
+	}
+}
+
+// AwaitAll waits for all futures to complete, returning their results in order. On the first error, or when ctx is
+// done, the remaining futures are cancelled and the error is returned.
+//
+//goland:noinspection GoUnusedExportedFunction
+func AwaitAll[A any](ctx context.Context, futures ...*Future[A]) ([]A, error) {
+	results := make([]A, len(futures))
+	for i, f := range futures {
+		r, err := f.Await(ctx)
+		if err != nil {
+			cancelAll(futures)
+			return nil, err
+		}
+		results[i] = r
+	}
+	return results, nil
+}
+
+// AwaitFirst returns the result of the first future to complete successfully and cancels the rest. When every future
+// fails, the joined errors are returned.
+//
+//goland:noinspection GoUnusedExportedFunction
+func AwaitFirst[A any](ctx context.Context, futures ...*Future[A]) (A, error) {
+	var zero A
+	defer cancelAll(futures)
+
+	type outcome struct {
+		result A
+		err    error
+	}
+	outcomes := make(chan outcome, len(futures))
+	for _, f := range futures {
+		go func(f *Future[A]) {
+			<-f.done
+			outcomes <- outcome{f.result, f.err}
+		}(f)
+	}
+
+	var errs []error
+	for range futures {
+		select {
+		case o := <-outcomes:
+			if o.err == nil {
+				return o.result, nil
+			}
+			errs = append(errs, o.err)
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		}
+	}
+	if len(errs) == 0 {
+		return zero, errors.New("no futures supplied")
+	}
+	return zero, errors.Join(errs...)
+}
+
+func cancelAll[A any](futures []*Future[A]) {
+	for _, f := range futures {
+		f.Cancel()
+	}
+}
@@ -0,0 +1,57 @@
+package requests_test
+
+import (
+	"context"
+	"errors"
+	"github.com/Chronicle20/atlas-rest/requests"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"testing"
+	"time"
+)
+
+func delayed(d time.Duration, v int, err error) requests.Request[int] {
+	return func(l logrus.FieldLogger, ctx context.Context) (int, error) {
+		select {
+		case <-time.After(d):
+			return v, err
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+}
+
+func TestAwaitAll(t *testing.T) {
+	l, _ := test.NewNullLogger()
+	ctx := context.Background()
+
+	rs, err := requests.AwaitAll(ctx, requests.Async(l, ctx, delayed(20*time.Millisecond, 1, nil)), requests.Async(l, ctx, delayed(0, 2, nil)))
+	if err != nil || len(rs) != 2 || rs[0] != 1 || rs[1] != 2 {
+		t.Fatalf("unexpected results [%v] [%v]", rs, err)
+	}
+
+	slow := requests.Async(l, ctx, delayed(time.Second, 1, nil))
+	_, err = requests.AwaitAll(ctx, requests.Async(l, ctx, delayed(0, 0, requests.ErrNotFound)), slow)
+	if !errors.Is(err, requests.ErrNotFound) {
+		t.Fatalf("expected not found, got [%v]", err)
+	}
+	_, err = slow.Await(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected remaining future to be cancelled, got [%v]", err)
+	}
+}
+
+func TestAwaitFirst(t *testing.T) {
+	l, _ := test.NewNullLogger()
+	ctx := context.Background()
+
+	r, err := requests.AwaitFirst(ctx, requests.Async(l, ctx, delayed(0, 0, requests.ErrNotFound)), requests.Async(l, ctx, delayed(10*time.Millisecond, 2, nil)), requests.Async(l, ctx, delayed(time.Second, 3, nil)))
+	if err != nil || r != 2 {
+		t.Fatalf("unexpected result [%d] [%v]", r, err)
+	}
+
+	_, err = requests.AwaitFirst(ctx, requests.Async(l, ctx, delayed(0, 0, requests.ErrNotFound)), requests.Async(l, ctx, delayed(0, 0, requests.ErrBadRequest)))
+	if !errors.Is(err, requests.ErrNotFound) || !errors.Is(err, requests.ErrBadRequest) {
+		t.Fatalf("expected joined errors, got [%v]", err)
+	}
+}
@@ -13,57 +13,79 @@ var ErrNotFound = errors.New("not found")
 
 type Request[A any] func(l logrus.FieldLogger, ctx context.Context) (A, error)
 
-func get[A any](l logrus.FieldLogger, ctx context.Context) func(url string, configurators ...Configurator) (A, error) {
-	return func(url string, configurators ...Configurator) (A, error) {
-		c := &configuration{retries: 1}
-		for _, configurator := range configurators {
-			configurator(c)
+func fetch(l logrus.FieldLogger, ctx context.Context, c *configuration, url string) (*http.Response, error) {
+	newRequest := func(ctx context.Context, url string) (*http.Request, error) {
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
 		}
 
-		var r *http.Response
-		get := func(attempt int) (bool, error) {
-			var err error
+		req.Header.Set("Accept", c.accept())
 
-			req, err := http.NewRequest(http.MethodGet, url, nil)
-			if err != nil {
-				l.WithError(err).Errorf("Error creating request.")
-				return true, err
-			}
+		c.decorate(ctx, req.Header)
 
-			for _, hd := range c.headerDecorators {
-				hd(req.Header)
-			}
+		return req.WithContext(ctx), nil
+	}
 
-			req = req.WithContext(ctx)
+	var r *http.Response
+	get := func(attempt int) (bool, error) {
+		var err error
 
-			l.Debugf("Issuing [%s] request to [%s].", req.Method, req.URL)
-			r, err = http.DefaultClient.Do(req)
+		if c.hedgePolicy != nil {
+			urls := append([]string{url}, c.hedgeUrls...)
+			if len(c.hedgeUrls) == 0 {
+				urls = append(urls, url)
+			}
+			r, err = hedge(l, ctx, c, newRequest, urls)
+		} else {
+			var req *http.Request
+			req, err = newRequest(ctx, url)
 			if err != nil {
-				l.WithError(err).Warnf("Failed calling [%s] on [%s], will retry.", http.MethodGet, url)
+				l.WithError(err).Errorf("Error creating request.")
 				return true, err
 			}
-			return false, nil
+			r, err = do(l, ctx, c, req)
 		}
-		err := retry.Try(get, c.retries)
+		if err == nil && r.StatusCode != http.StatusOK && r.StatusCode != http.StatusAccepted {
+			if serr := statusError(http.MethodGet, r.StatusCode); c.retryable(serr) {
+				_ = r.Body.Close()
+				err = serr
+			}
+		}
+		if err != nil {
+			if !c.retryable(err) {
+				return false, err
+			}
+			l.WithError(err).Warnf("Failed calling [%s] on [%s], will retry.", http.MethodGet, c.policy().URL(url))
+			return true, err
+		}
+		return false, nil
+	}
+	err := retry.Try(get, c.retries)
+	if err != nil {
+		l.WithError(err).Errorf("Unable to successfully call [%s] on [%s].", http.MethodGet, c.policy().URL(url))
+		return nil, err
+	}
+	if r.StatusCode == http.StatusOK || r.StatusCode == http.StatusAccepted {
+		return r, nil
+	}
+	_ = r.Body.Close()
+	l.Debugf("Unable to successfully call [%s] on [%s], returned status code [%d].", http.MethodGet, c.policy().URL(url), r.StatusCode)
+	return nil, statusError(http.MethodGet, r.StatusCode)
+}
+
+func get[A any](l logrus.FieldLogger, ctx context.Context) func(url string, configurators ...Configurator) (A, error) {
+	return func(url string, configurators ...Configurator) (A, error) {
+		c := newConfiguration(configurators...)
 
 		var resp A
+		r, err := fetch(l, ctx, c, url)
 		if err != nil {
-			l.WithError(err).Errorf("Unable to successfully call [%s] on [%s].", http.MethodGet, url)
-			return resp, err
-		}
-		if r.StatusCode == http.StatusOK || r.StatusCode == http.StatusAccepted {
-			resp, err = processResponse[A](r)
-			l.WithFields(logrus.Fields{"method": http.MethodGet, "status": r.Status, "path": url, "response": resp}).Debugf("Printing request.")
 			return resp, err
 		}
-		if r.StatusCode == http.StatusBadRequest {
-			return resp, ErrBadRequest
-		}
-		if r.StatusCode == http.StatusNotFound {
-			return resp, ErrNotFound
-		}
-		l.Debugf("Unable to successfully call [%s] on [%s], returned status code [%d].", http.MethodGet, url, r.StatusCode)
-		return resp, errors.New("unknown error")
+		resp, err = processResponse[A](c, r)
+		l.WithFields(logrus.Fields{"method": http.MethodGet, "status": r.Status, "path": c.policy().URL(url), "response": c.policy().Defer(resp)}).Debugf("Printing request.")
+		return resp, err
 	}
 }
 
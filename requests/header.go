@@ -2,13 +2,18 @@ package requests
 
 import (
 	"context"
+	"github.com/Chronicle20/atlas-rest/requestid"
+	"github.com/Chronicle20/atlas-rest/tenantbaggage"
 	"github.com/Chronicle20/atlas-tenant"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/propagation"
 	"net/http"
 	"strconv"
+	"sync/atomic"
 )
 
+const IdempotencyKeyHeader = "Idempotency-Key"
+
 type HeaderDecorator func(header http.Header)
 
 //goland:noinspection GoUnusedExportedFunction
@@ -23,19 +28,101 @@ func SpanHeaderDecorator(ctx context.Context) HeaderDecorator {
 	}
 }
 
+type tenantPropagation struct {
+	headers bool
+	baggage bool
+}
+
+type TenantPropagationConfigurator func(tp *tenantPropagation)
+
+var defaultTenantPropagation atomic.Pointer[tenantPropagation]
+
+func init() {
+	defaultTenantPropagation.Store(&tenantPropagation{headers: true})
+}
+
+// SetDefaultTenantPropagation changes how the tenant is propagated by every TenantHeaderDecorator, including the one
+// applied automatically from the context, unless overridden.
+//
 //goland:noinspection GoUnusedExportedFunction
-func TenantHeaderDecorator(ctx context.Context) HeaderDecorator {
-	return func(h http.Header) {
-		h.Set("Content-Type", "application/json; charset=utf-8")
+func SetDefaultTenantPropagation(configurators ...TenantPropagationConfigurator) {
+	tp := &tenantPropagation{headers: true}
+	for _, configurator := range configurators {
+		configurator(tp)
+	}
+	defaultTenantPropagation.Store(tp)
+}
 
+// SetTenantPropagation changes how the tenant found in the context is propagated for requests made with this
+// configuration.
+//
+//goland:noinspection GoUnusedExportedFunction
+func SetTenantPropagation(configurators ...TenantPropagationConfigurator) Configurator {
+	return func(c *configuration) {
+		c.tenantPropagation = configurators
+	}
+}
+
+// EmitTenantHeaders controls whether the tenant is carried in the custom tenant headers. Enabled by default.
+//
+//goland:noinspection GoUnusedExportedFunction
+func EmitTenantHeaders(enabled bool) TenantPropagationConfigurator {
+	return func(tp *tenantPropagation) {
+		tp.headers = enabled
+	}
+}
+
+// EmitTenantBaggage controls whether the tenant is carried in W3C Baggage. Disabled by default.
+//
+//goland:noinspection GoUnusedExportedFunction
+func EmitTenantBaggage(enabled bool) TenantPropagationConfigurator {
+	return func(tp *tenantPropagation) {
+		tp.baggage = enabled
+	}
+}
+
+//goland:noinspection GoUnusedExportedFunction
+func TenantHeaderDecorator(ctx context.Context, configurators ...TenantPropagationConfigurator) HeaderDecorator {
+	tp := *defaultTenantPropagation.Load()
+	for _, configurator := range configurators {
+		configurator(&tp)
+	}
+
+	return func(h http.Header) {
 		t, err := tenant.FromContext(ctx)()
 		if err != nil {
 			return
 		}
 
-		h.Set(tenant.ID, t.Id().String())
-		h.Set(tenant.Region, t.Region())
-		h.Set(tenant.MajorVersion, strconv.Itoa(int(t.MajorVersion())))
-		h.Set(tenant.MinorVersion, strconv.Itoa(int(t.MinorVersion())))
+		if tp.headers {
+			h.Set(tenant.ID, t.Id().String())
+			h.Set(tenant.Region, t.Region())
+			h.Set(tenant.MajorVersion, strconv.Itoa(int(t.MajorVersion())))
+			h.Set(tenant.MinorVersion, strconv.Itoa(int(t.MinorVersion())))
+		}
+		if tp.baggage {
+			_ = tenantbaggage.Inject(h, t)
+		}
+	}
+}
+
+//goland:noinspection GoUnusedExportedFunction
+func RequestIdHeaderDecorator(ctx context.Context) HeaderDecorator {
+	return func(h http.Header) {
+		id, err := requestid.FromContext(ctx)()
+		if err != nil {
+			return
+		}
+		h.Set(requestid.Header, id)
+	}
+}
+
+// ContextHeaderDecorators are applied to every request unless disabled, propagating the span, tenant and request id
+// found in the context. The tenant is propagated as configured by SetDefaultTenantPropagation, unless overridden.
+func ContextHeaderDecorators(ctx context.Context, configurators ...TenantPropagationConfigurator) []HeaderDecorator {
+	return []HeaderDecorator{
+		SpanHeaderDecorator(ctx),
+		TenantHeaderDecorator(ctx, configurators...),
+		RequestIdHeaderDecorator(ctx),
 	}
 }
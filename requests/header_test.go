@@ -0,0 +1,96 @@
+package requests_test
+
+import (
+	"context"
+	"github.com/Chronicle20/atlas-rest/requestid"
+	"github.com/Chronicle20/atlas-rest/requests"
+	"github.com/Chronicle20/atlas-rest/tenantbaggage"
+	"github.com/Chronicle20/atlas-tenant"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus/hooks/test"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestContextHeaderDecorators(t *testing.T) {
+	l, _ := test.NewNullLogger()
+
+	var received http.Header
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer s.Close()
+
+	it, err := tenant.Create(uuid.New(), "GMS", 83, 1)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	ctx := requestid.WithContext(tenant.WithContext(context.Background(), it), "atlas-request")
+
+	_, _ = requests.MakeGetRequest[character](s.URL)(l, ctx)
+	if received.Get(tenant.ID) != it.Id().String() || received.Get(requestid.Header) != "atlas-request" {
+		t.Fatalf("expected context to be propagated, got [%v]", received)
+	}
+
+	_, _ = requests.MakeGetRequest[character](s.URL, requests.DisableContextDecorators())(l, ctx)
+	if received.Get(tenant.ID) != "" || received.Get(requestid.Header) != "" {
+		t.Fatalf("expected context not to be propagated, got [%v]", received)
+	}
+}
+
+func TestContextTenantPropagation(t *testing.T) {
+	l, _ := test.NewNullLogger()
+
+	var received http.Header
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer s.Close()
+
+	it, err := tenant.Create(uuid.New(), "GMS", 83, 1)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	ctx := requestid.WithContext(tenant.WithContext(context.Background(), it), "atlas-request")
+
+	_, _ = requests.MakeGetRequest[character](s.URL, requests.SetTenantPropagation(requests.EmitTenantHeaders(false), requests.EmitTenantBaggage(true)))(l, ctx)
+	if received.Get(tenant.ID) != "" || received.Get("baggage") == "" || received.Get(requestid.Header) != "atlas-request" {
+		t.Fatalf("expected tenant to be propagated only in baggage, got [%v]", received)
+	}
+
+	requests.SetDefaultTenantPropagation(requests.EmitTenantHeaders(false), requests.EmitTenantBaggage(true))
+	defer requests.SetDefaultTenantPropagation()
+
+	_, _ = requests.MakeGetRequest[character](s.URL)(l, ctx)
+	if received.Get(tenant.ID) != "" || received.Get("baggage") == "" {
+		t.Fatalf("expected default propagation to apply, got [%v]", received)
+	}
+
+	_, _ = requests.MakeGetRequest[character](s.URL, requests.SetTenantPropagation(requests.EmitTenantHeaders(true)))(l, ctx)
+	if received.Get(tenant.ID) != it.Id().String() {
+		t.Fatalf("expected per call propagation to override default, got [%v]", received)
+	}
+}
+
+func TestTenantBaggageKeepsInvalidBaggage(t *testing.T) {
+	it, err := tenant.Create(uuid.New(), "GMS", 83, 1)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	ctx := tenant.WithContext(context.Background(), it)
+
+	h := http.Header{}
+	h.Set(tenantbaggage.Header, "invalid baggage")
+	requests.TenantHeaderDecorator(ctx, requests.EmitTenantBaggage(true))(h)
+
+	if v := h.Values(tenantbaggage.Header); len(v) != 2 || v[0] != "invalid baggage" {
+		t.Fatalf("expected invalid baggage to be kept, got [%v]", v)
+	}
+	et, err := tenantbaggage.Extract(h)
+	if err != nil || et.Id() != it.Id() {
+		t.Fatalf("expected tenant to be extracted alongside invalid baggage, got [%v]", err)
+	}
+}
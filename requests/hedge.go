@@ -0,0 +1,259 @@
+package requests
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type HedgeConfigurator func(p *HedgePolicy)
+
+// HedgePolicy is shared across calls so that observed latencies drive the hedge delay and the number of
+// hedges in flight is capped for all requests using it.
+type HedgePolicy struct {
+	percentile   float64
+	initialDelay time.Duration
+	minSamples   int
+	window       int
+	maxInFlight  int64
+
+	inFlight atomic.Int64
+	mu       sync.Mutex
+	samples  []time.Duration
+	next     int
+}
+
+//goland:noinspection GoUnusedExportedFunction
+func NewHedgePolicy(configurators ...HedgeConfigurator) *HedgePolicy {
+	p := &HedgePolicy{
+		percentile:   0.95,
+		initialDelay: time.Duration(100) * time.Millisecond,
+		minSamples:   20,
+		window:       100,
+		maxInFlight:  10,
+	}
+	for _, configurator := range configurators {
+		configurator(p)
+	}
+	p.samples = make([]time.Duration, 0, p.window)
+	return p
+}
+
+//goland:noinspection GoUnusedExportedFunction
+func SetHedgePercentile(percentile float64) HedgeConfigurator {
+	return func(p *HedgePolicy) {
+		p.percentile = percentile
+	}
+}
+
+//goland:noinspection GoUnusedExportedFunction
+func SetHedgeInitialDelay(delay time.Duration) HedgeConfigurator {
+	return func(p *HedgePolicy) {
+		p.initialDelay = delay
+	}
+}
+
+//goland:noinspection GoUnusedExportedFunction
+func SetHedgeMinSamples(amount int) HedgeConfigurator {
+	return func(p *HedgePolicy) {
+		p.minSamples = amount
+	}
+}
+
+//goland:noinspection GoUnusedExportedFunction
+func SetHedgeWindow(size int) HedgeConfigurator {
+	return func(p *HedgePolicy) {
+		p.window = size
+	}
+}
+
+//goland:noinspection GoUnusedExportedFunction
+func SetHedgeMaxInFlight(amount int64) HedgeConfigurator {
+	return func(p *HedgePolicy) {
+		p.maxInFlight = amount
+	}
+}
+
+// SetHedging hedges idempotent requests using the supplied policy. Hedged requests are issued to the alternate
+// urls in order, or to the original url when none are supplied.
+//
+//goland:noinspection GoUnusedExportedFunction
+func SetHedging(p *HedgePolicy, alternates ...string) Configurator {
+	return func(c *configuration) {
+		c.hedgePolicy = p
+		c.hedgeUrls = alternates
+	}
+}
+
+func (p *HedgePolicy) Delay() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.samples) < p.minSamples || len(p.samples) == 0 {
+		return p.initialDelay
+	}
+	sorted := make([]time.Duration, len(p.samples))
+	copy(sorted, p.samples)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	idx := int(float64(len(sorted)-1) * p.percentile)
+	return sorted[idx]
+}
+
+func (p *HedgePolicy) InFlight() int64 {
+	return p.inFlight.Load()
+}
+
+func (p *HedgePolicy) observe(d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.window <= 0 {
+		return
+	}
+	if len(p.samples) < p.window {
+		p.samples = append(p.samples, d)
+		return
+	}
+	p.samples[p.next] = d
+	p.next = (p.next + 1) % p.window
+}
+
+func (p *HedgePolicy) acquire() bool {
+	for {
+		current := p.inFlight.Load()
+		if current >= p.maxInFlight {
+			return false
+		}
+		if p.inFlight.CompareAndSwap(current, current+1) {
+			return true
+		}
+	}
+}
+
+func (p *HedgePolicy) release() {
+	p.inFlight.Add(-1)
+}
+
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b cancelOnCloseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+type hedgeResult struct {
+	index int
+	r     *http.Response
+	err   error
+}
+
+// hedge issues the request to the first url, hedging to the remaining urls while no response arrives. The first 2xx
+// response wins, and only its latency is observed. Any other response is returned only once every attempt has
+// completed without success.
+func hedge(l logrus.FieldLogger, ctx context.Context, c *configuration, newRequest func(ctx context.Context, url string) (*http.Request, error), urls []string) (*http.Response, error) {
+	p := c.hedgePolicy
+	results := make(chan hedgeResult, len(urls))
+	cancels := make([]context.CancelFunc, 0, len(urls))
+	starts := make([]time.Time, 0, len(urls))
+
+	launch := func(index int, hedged bool) {
+		actx, cancel := context.WithCancel(ctx)
+		cancels = append(cancels, cancel)
+		starts = append(starts, time.Now())
+		go func() {
+			var res hedgeResult
+			req, err := newRequest(actx, urls[index])
+			if err != nil {
+				res = hedgeResult{index: index, err: err}
+			} else {
+				r, err := do(l, actx, c, req)
+				res = hedgeResult{index: index, r: r, err: err}
+			}
+			// released ahead of the result so the hedge is no longer counted once the call returns.
+			if hedged {
+				p.release()
+			}
+			results <- res
+		}()
+	}
+
+	launch(0, false)
+	pending := 1
+	next := 1
+
+	timer := time.NewTimer(p.Delay())
+	defer timer.Stop()
+
+	var err error
+	var fallback *hedgeResult
+	for pending > 0 {
+		select {
+		case <-timer.C:
+			if next >= len(urls) {
+				continue
+			}
+			if !p.acquire() {
+				l.Debugf("Hedge limit reached, not hedging request to [%s].", c.policy().URL(urls[0]))
+				continue
+			}
+			l.Debugf("No response from [%s] after [%s], hedging request to [%s].", c.policy().URL(urls[0]), time.Since(starts[0]), c.policy().URL(urls[next]))
+			launch(next, true)
+			pending++
+			next++
+			timer.Reset(p.Delay())
+		case res := <-results:
+			pending--
+			if res.err != nil {
+				err = res.err
+				continue
+			}
+			if res.r.StatusCode < 200 || res.r.StatusCode >= 300 {
+				if fallback != nil {
+					_ = res.r.Body.Close()
+					continue
+				}
+				fallback = &res
+				continue
+			}
+			if fallback != nil {
+				_ = fallback.r.Body.Close()
+			}
+			p.observe(time.Since(starts[res.index]))
+			return settle(res, cancels, results, pending), nil
+		}
+	}
+	if fallback != nil {
+		return settle(*fallback, cancels, results, pending), nil
+	}
+	for _, cancel := range cancels {
+		cancel()
+	}
+	return nil, err
+}
+
+// settle cancels the attempts other than the winner, closing any responses they still produce, and ties the
+// cancellation of the winner to its body being closed.
+func settle(res hedgeResult, cancels []context.CancelFunc, results <-chan hedgeResult, pending int) *http.Response {
+	for i, cancel := range cancels {
+		if i != res.index {
+			cancel()
+		}
+	}
+	go func() {
+		for i := 0; i < pending; i++ {
+			lr := <-results
+			if lr.r != nil {
+				_ = lr.r.Body.Close()
+			}
+		}
+	}()
+	res.r.Body = cancelOnCloseBody{ReadCloser: res.r.Body, cancel: cancels[res.index]}
+	return res.r
+}
@@ -0,0 +1,100 @@
+package requests_test
+
+import (
+	"context"
+	"errors"
+	"github.com/Chronicle20/atlas-rest/requests"
+	"github.com/sirupsen/logrus/hooks/test"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type origin struct {
+	Origin string `json:"origin"`
+}
+
+func TestHedgedRequest(t *testing.T) {
+	l, _ := test.NewNullLogger()
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
+		_, _ = w.Write([]byte(`{"origin":"slow"}`))
+	}))
+	defer slow.Close()
+
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"origin":"fast"}`))
+	}))
+	defer fast.Close()
+
+	p := requests.NewHedgePolicy(requests.SetHedgeInitialDelay(10 * time.Millisecond))
+
+	start := time.Now()
+	o, err := requests.MakeGetRequest[origin](slow.URL, requests.SetCodec(requests.JsonCodec{}), requests.SetHedging(p, fast.URL))(l, context.Background())
+	if err != nil || o.Origin != "fast" {
+		t.Fatalf("expected hedged response, got [%v] [%v]", o, err)
+	}
+	if time.Since(start) > time.Second {
+		t.Fatal("hedged request did not return early")
+	}
+	if p.InFlight() != 0 {
+		t.Fatalf("expected no hedges in flight, got [%d]", p.InFlight())
+	}
+}
+
+func TestHedgeErrorStatusDoesNotWin(t *testing.T) {
+	l, _ := test.NewNullLogger()
+
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		_, _ = w.Write([]byte(`{"origin":"primary"}`))
+	}))
+	defer primary.Close()
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+
+	p := requests.NewHedgePolicy(requests.SetHedgeInitialDelay(10*time.Millisecond), requests.SetHedgeMinSamples(1))
+
+	o, err := requests.MakeGetRequest[origin](primary.URL, requests.SetCodec(requests.JsonCodec{}), requests.SetHedging(p, failing.URL))(l, context.Background())
+	if err != nil || o.Origin != "primary" {
+		t.Fatalf("expected successful response to win over error status, got [%v] [%v]", o, err)
+	}
+	if p.Delay() < 50*time.Millisecond {
+		t.Fatalf("expected only the winning latency to be observed, got delay [%s]", p.Delay())
+	}
+
+	_, err = requests.MakeGetRequest[origin](failing.URL, requests.SetHedging(p, failing.URL))(l, context.Background())
+	if !requests.IsServerError(err) {
+		t.Fatalf("expected error status once every attempt fails, got [%v]", err)
+	}
+}
+
+func TestHedgeLimit(t *testing.T) {
+	l, _ := test.NewNullLogger()
+
+	calls := make(chan struct{}, 10)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls <- struct{}{}
+		time.Sleep(50 * time.Millisecond)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer s.Close()
+
+	p := requests.NewHedgePolicy(requests.SetHedgeInitialDelay(time.Millisecond), requests.SetHedgeMaxInFlight(0))
+
+	_, err := requests.MakeGetRequest[interface{}](s.URL, requests.SetHedging(p))(l, context.Background())
+	if !errors.Is(err, requests.ErrNotFound) {
+		t.Fatalf("expected not found, got [%v]", err)
+	}
+	if len(calls) != 1 {
+		t.Fatalf("expected a single call, got [%d]", len(calls))
+	}
+}
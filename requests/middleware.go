@@ -0,0 +1,57 @@
+package requests
+
+import (
+	"net/http"
+	"sync"
+)
+
+// RoundTrip issues a single outbound request.
+type RoundTrip func(req *http.Request) (*http.Response, error)
+
+// Middleware wraps a RoundTrip, and may inspect or alter the request, observe the response, or short-circuit the
+// call entirely by not invoking next, in which case it is responsible for closing the request body.
+type Middleware func(next RoundTrip) RoundTrip
+
+var globalMiddleware = struct {
+	sync.RWMutex
+	chain []Middleware
+}{}
+
+// SetGlobalMiddleware replaces the middleware applied to every outbound request, ahead of any supplied per call.
+//
+//goland:noinspection GoUnusedExportedFunction
+func SetGlobalMiddleware(ms ...Middleware) {
+	globalMiddleware.Lock()
+	defer globalMiddleware.Unlock()
+	globalMiddleware.chain = append([]Middleware{}, ms...)
+}
+
+//goland:noinspection GoUnusedExportedFunction
+func AddGlobalMiddleware(m Middleware) {
+	globalMiddleware.Lock()
+	defer globalMiddleware.Unlock()
+	globalMiddleware.chain = append(globalMiddleware.chain, m)
+}
+
+// AddMiddleware applies the middleware to requests made with this configuration. Middleware added first is
+// outermost.
+//
+//goland:noinspection GoUnusedExportedFunction
+func AddMiddleware(m Middleware) Configurator {
+	return func(c *configuration) {
+		c.middleware = append(c.middleware, m)
+	}
+}
+
+// chain wraps the terminal RoundTrip with the global middleware followed by that of the configuration.
+func (c *configuration) chain(terminal RoundTrip) RoundTrip {
+	globalMiddleware.RLock()
+	ms := append(append([]Middleware{}, globalMiddleware.chain...), c.middleware...)
+	globalMiddleware.RUnlock()
+
+	rt := terminal
+	for i := len(ms) - 1; i >= 0; i-- {
+		rt = ms[i](rt)
+	}
+	return rt
+}
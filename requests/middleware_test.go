@@ -0,0 +1,65 @@
+package requests_test
+
+import (
+	"context"
+	"github.com/Chronicle20/atlas-rest/requests"
+	"github.com/sirupsen/logrus/hooks/test"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	l, _ := test.NewNullLogger()
+
+	calls := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		_, _ = w.Write([]byte(`{"data":{"type":"characters","id":"1","attributes":{"name":"` + r.Header.Get("X-Name") + `"}}}`))
+	}))
+	defer s.Close()
+
+	var order []string
+	trace := func(name string) requests.Middleware {
+		return func(next requests.RoundTrip) requests.RoundTrip {
+			return func(req *http.Request) (*http.Response, error) {
+				order = append(order, name)
+				req.Header.Set("X-Name", req.Header.Get("X-Name")+name)
+				r, err := next(req)
+				if err == nil {
+					order = append(order, name+":"+r.Status)
+				}
+				return r, err
+			}
+		}
+	}
+
+	requests.SetGlobalMiddleware(trace("global"))
+	defer requests.SetGlobalMiddleware()
+
+	c, err := requests.MakeGetRequest[character](s.URL, requests.AddMiddleware(trace("call")))(l, context.Background())
+	if err != nil || c.Name != "globalcall" {
+		t.Fatalf("unexpected result [%v] [%v]", c, err)
+	}
+	if strings.Join(order, ",") != "global,call,call:200 OK,global:200 OK" {
+		t.Fatalf("unexpected middleware order [%v]", order)
+	}
+
+	cached := func(next requests.RoundTrip) requests.RoundTrip {
+		return func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				Status:     "200 OK",
+				StatusCode: http.StatusOK,
+				Header:     http.Header{},
+				Body:       io.NopCloser(strings.NewReader(`{"data":{"type":"characters","id":"1","attributes":{"name":"cached"}}}`)),
+				Request:    req,
+			}, nil
+		}
+	}
+	c, err = requests.MakeGetRequest[character](s.URL, requests.AddMiddleware(cached))(l, context.Background())
+	if err != nil || c.Name != "cached" || calls != 1 {
+		t.Fatalf("expected short-circuited response, got [%v] [%v] after [%d] calls", c, err, calls)
+	}
+}
@@ -1,10 +1,10 @@
 package requests
 
 import (
-	"bytes"
 	"context"
+	"errors"
 	"github.com/Chronicle20/atlas-rest/retry"
-	"github.com/jtumidanski/api2go/jsonapi"
+	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 	"net/http"
 )
@@ -12,60 +12,111 @@ import (
 func createOrUpdate[A any](l logrus.FieldLogger, ctx context.Context) func(method string) func(url string, input interface{}, configurators ...Configurator) (A, error) {
 	return func(method string) func(url string, input interface{}, configurators ...Configurator) (A, error) {
 		return func(url string, input interface{}, configurators ...Configurator) (A, error) {
-			c := &configuration{retries: 1}
-			for _, configurator := range configurators {
-				configurator(c)
-			}
+			c := newConfiguration(configurators...)
 
 			var result A
-			jsonReq, err := jsonapi.Marshal(input)
+			body, err := c.codec.Marshal(input)
 			if err != nil {
 				return result, err
 			}
 
-			var r *http.Response
-			post := func(attempt int) (bool, error) {
-				var err error
-
-				req, err := http.NewRequest(method, url, bytes.NewReader(jsonReq))
+			encoding := ""
+			if c.requestEncoding != "" && len(body) >= c.requestCompressionMinSize {
+				body, err = compress(c.requestEncoding, body)
 				if err != nil {
-					l.WithError(err).Errorf("Error creating request.")
-					return true, err
+					return result, err
 				}
+				encoding = c.requestEncoding
+			}
 
-				for _, hd := range c.headerDecorators {
-					hd(req.Header)
-				}
+			return exchange[A](l, ctx, c, method, url, c.codec.ContentType(), encoding, BytesSource(body), input)
+		}
+	}
+}
 
-				req = req.WithContext(ctx)
+// exchange issues a request with a body read from the source on each attempt, decoding the response with the
+// configured codec. The input is used only for logging.
+func exchange[A any](l logrus.FieldLogger, ctx context.Context, c *configuration, method string, url string, contentType string, encoding string, source BodySource, input interface{}) (A, error) {
+	var result A
 
-				l.Debugf("Issuing [%s] request to [%s].", method, req.URL)
-				r, err = http.DefaultClient.Do(req)
-				if err != nil {
-					l.WithError(err).Warnf("Failed calling [%s] on [%s], will retry.", method, url)
-					return true, err
-				}
-				return false, nil
-			}
-			err = retry.Try(post, c.retries)
-			if err != nil {
-				l.WithError(err).Errorf("Unable to successfully call [%s] on [%s].", method, url)
-				return result, err
+	key := c.idempotencyKey
+	if key == "" && !c.disableIdempotencyKey {
+		key = uuid.New().String()
+	}
+
+	var r *http.Response
+	post := func(attempt int) (bool, error) {
+		req, err := newBodyRequest(method, url, source, c.progress)
+		if err != nil {
+			if errors.Is(err, ErrBodyConsumed) {
+				return false, err
 			}
+			l.WithError(err).Errorf("Error creating request.")
+			return true, err
+		}
 
-			if r.ContentLength == 0 {
-				l.WithFields(logrus.Fields{"method": method, "status": r.Status, "path": url, "input": input, "response": ""}).Debugf("Printing request.")
-			} else {
-				result, err = processResponse[A](r)
-				if err != nil {
-					return result, err
-				}
-				l.WithFields(logrus.Fields{"method": method, "status": r.Status, "path": url, "input": input, "response": result}).Debugf("Printing request.")
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("Accept", c.accept())
+		if encoding != "" {
+			req.Header.Set("Content-Encoding", encoding)
+		}
+
+		c.decorate(ctx, req.Header)
+
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		if c.ifMatch != "" {
+			req.Header.Set(IfMatchHeader, c.ifMatch)
+		}
+
+		req = req.WithContext(ctx)
+
+		r, err = do(l, ctx, c, req)
+		if err == nil && key != "" {
+			if ierr := idempotencyError(method, r); ierr != nil {
+				_ = r.Body.Close()
+				err = ierr
 			}
+		}
+		if err == nil && c.rejectsStatus(r.StatusCode) {
+			if serr := statusError(method, r.StatusCode); c.retryable(serr) {
+				_ = r.Body.Close()
+				err = serr
+			}
+		}
+		if err != nil {
+			if !c.retryable(err) {
+				return false, err
+			}
+			l.WithError(err).Warnf("Failed calling [%s] on [%s], will retry.", method, c.policy().URL(url))
+			return true, err
+		}
+		return false, nil
+	}
+	err := retry.Try(post, c.retries)
+	if err != nil {
+		l.WithError(err).Errorf("Unable to successfully call [%s] on [%s].", method, c.policy().URL(url))
+		return result, err
+	}
+	if c.rejectsStatus(r.StatusCode) {
+		_ = r.Body.Close()
+		l.Debugf("Unable to successfully call [%s] on [%s], returned status code [%d].", method, c.policy().URL(url), r.StatusCode)
+		return result, statusError(method, r.StatusCode)
+	}
 
-			return result, nil
+	if r.ContentLength == 0 {
+		_ = r.Body.Close()
+		l.WithFields(logrus.Fields{"method": method, "status": r.Status, "path": c.policy().URL(url), "input": c.policy().Defer(input), "response": ""}).Debugf("Printing request.")
+	} else {
+		result, err = processResponse[A](c, r)
+		if err != nil {
+			return result, err
 		}
+		l.WithFields(logrus.Fields{"method": method, "status": r.Status, "path": c.policy().URL(url), "input": c.policy().Defer(input), "response": c.policy().Defer(result)}).Debugf("Printing request.")
 	}
+
+	return result, nil
 }
 
 //goland:noinspection GoUnusedExportedFunction
@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/Chronicle20/atlas-model/model"
 	"github.com/sirupsen/logrus"
+	"sync"
 )
 
 //goland:noinspection GoUnusedExportedFunction
@@ -28,3 +29,38 @@ func SliceProvider[A any, M any](l logrus.FieldLogger, ctx context.Context) func
 		return model.FilteredProvider[M](sm, filters)
 	}
 }
+
+// LazyProvider behaves as Provider, but defers issuing the request until the resulting provider is first invoked.
+// The outcome of the request is memoized, so repeated invocations share a single call.
+//
+//goland:noinspection GoUnusedExportedFunction
+func LazyProvider[A any, M any](l logrus.FieldLogger, ctx context.Context) func(r Request[A], t model.Transformer[A, M]) model.Provider[M] {
+	return func(r Request[A], t model.Transformer[A, M]) model.Provider[M] {
+		return model.Map[A, M](t)(memoize(l, ctx, r))
+	}
+}
+
+// LazySliceProvider behaves as SliceProvider, but defers issuing the request until the resulting provider is first
+// invoked. The outcome of the request is memoized, so repeated invocations share a single call.
+//
+//goland:noinspection GoUnusedExportedFunction
+func LazySliceProvider[A any, M any](l logrus.FieldLogger, ctx context.Context) func(r Request[[]A], t model.Transformer[A, M], filters []model.Filter[M]) model.Provider[[]M] {
+	return func(r Request[[]A], t model.Transformer[A, M], filters []model.Filter[M]) model.Provider[[]M] {
+		return model.FilteredProvider[M](model.SliceMap[A, M](t)(memoize(l, ctx, r))(), filters)
+	}
+}
+
+func memoize[A any](l logrus.FieldLogger, ctx context.Context, r Request[A]) model.Provider[A] {
+	var once sync.Once
+	var result A
+	var err error
+	return func() (A, error) {
+		once.Do(func() {
+			if err = ctx.Err(); err != nil {
+				return
+			}
+			result, err = r(l, ctx)
+		})
+		return result, err
+	}
+}
@@ -0,0 +1,85 @@
+package requests_test
+
+import (
+	"context"
+	"errors"
+	"github.com/Chronicle20/atlas-rest/requests"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"testing"
+)
+
+func TestLazyProvider(t *testing.T) {
+	l, _ := test.NewNullLogger()
+
+	calls := 0
+	r := func(l logrus.FieldLogger, ctx context.Context) (int, error) {
+		calls++
+		return 2, nil
+	}
+	double := func(v int) (int, error) {
+		return v * 2, nil
+	}
+
+	p := requests.LazyProvider[int, int](l, context.Background())(r, double)
+	if calls != 0 {
+		t.Fatal("expected request to be deferred until invocation")
+	}
+	for i := 0; i < 2; i++ {
+		v, err := p()
+		if err != nil || v != 4 {
+			t.Fatalf("unexpected result [%d] [%v]", v, err)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("expected request to be memoized, issued [%d]", calls)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p = requests.LazyProvider[int, int](l, ctx)(r, double)
+	cancel()
+	_, err := p()
+	if !errors.Is(err, context.Canceled) || calls != 1 {
+		t.Fatalf("expected cancelled context to short-circuit, got [%v]", err)
+	}
+}
+
+func TestFallback(t *testing.T) {
+	l, _ := test.NewNullLogger()
+	ctx := context.Background()
+
+	notFound := func(l logrus.FieldLogger, ctx context.Context) (int, error) {
+		return 0, requests.ErrNotFound
+	}
+	badRequest := func(l logrus.FieldLogger, ctx context.Context) (int, error) {
+		return 0, requests.ErrBadRequest
+	}
+	found := func(l logrus.FieldLogger, ctx context.Context) (int, error) {
+		return 7, nil
+	}
+
+	v, err := requests.DefaultOnNotFound(notFound, 3)(l, ctx)
+	if err != nil || v != 3 {
+		t.Fatalf("expected default value, got [%d] [%v]", v, err)
+	}
+
+	_, err = requests.Fallback(badRequest, found, requests.ErrNotFound)(l, ctx)
+	if !errors.Is(err, requests.ErrBadRequest) {
+		t.Fatalf("expected unmatched error to be returned, got [%v]", err)
+	}
+
+	v, err = requests.FirstSuccessful(notFound, badRequest, found)(l, ctx)
+	if err != nil || v != 7 {
+		t.Fatalf("expected first successful result, got [%d] [%v]", v, err)
+	}
+
+	_, err = requests.FirstSuccessful(notFound, badRequest)(l, ctx)
+	if !errors.Is(err, requests.ErrNotFound) || !errors.Is(err, requests.ErrBadRequest) {
+		t.Fatalf("expected joined errors, got [%v]", err)
+	}
+
+	v, err = requests.DefaultProviderOnNotFound(requests.Provider[int, int](l, ctx)(notFound, func(v int) (int, error) { return v, nil }), 5)()
+	if err != nil || v != 5 {
+		t.Fatalf("expected default provider value, got [%d] [%v]", v, err)
+	}
+}
@@ -0,0 +1,167 @@
+package requests
+
+import (
+	"context"
+	"errors"
+	"github.com/Chronicle20/atlas-tenant"
+	"math"
+	"sync"
+	"time"
+)
+
+var ErrRateLimited = errors.New("rate limited")
+
+type RateLimiterConfigurator func(rl *RateLimiter)
+
+// RateLimiter is a token bucket guarding calls to a single destination service. When configured per tenant, each
+// tenant found in the request context receives its own bucket. Buckets which have refilled are evicted periodically, as
+// a full bucket is recreated as it was.
+type RateLimiter struct {
+	rate      float64
+	burst     float64
+	perTenant bool
+	failFast  bool
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	nextSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+//goland:noinspection GoUnusedExportedFunction
+func NewRateLimiter(rate float64, burst int, configurators ...RateLimiterConfigurator) *RateLimiter {
+	rl := &RateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*bucket),
+	}
+	for _, configurator := range configurators {
+		configurator(rl)
+	}
+	return rl
+}
+
+//goland:noinspection GoUnusedExportedFunction
+func SetRateLimitPerTenant() RateLimiterConfigurator {
+	return func(rl *RateLimiter) {
+		rl.perTenant = true
+	}
+}
+
+//goland:noinspection GoUnusedExportedFunction
+func SetRateLimitFailFast() RateLimiterConfigurator {
+	return func(rl *RateLimiter) {
+		rl.failFast = true
+	}
+}
+
+//goland:noinspection GoUnusedExportedFunction
+func SetRateLimiter(rl *RateLimiter) Configurator {
+	return func(c *configuration) {
+		c.rateLimiter = rl
+	}
+}
+
+func (rl *RateLimiter) key(ctx context.Context) string {
+	if !rl.perTenant {
+		return ""
+	}
+	t, err := tenant.FromContext(ctx)()
+	if err != nil {
+		return ""
+	}
+	return t.Id().String()
+}
+
+func (rl *RateLimiter) refill(key string, now time.Time) *bucket {
+	if now.After(rl.nextSweep) {
+		rl.sweep(now)
+	}
+	b, ok := rl.buckets[key]
+	if !ok {
+		b = &bucket{tokens: rl.burst, last: now}
+		rl.buckets[key] = b
+		return b
+	}
+	b.tokens = math.Min(rl.burst, b.tokens+now.Sub(b.last).Seconds()*rl.rate)
+	b.last = now
+	return b
+}
+
+// sweep evicts the buckets which are full by now, at most once per the time an empty bucket takes to refill, or per
+// second for limiters which refill faster.
+func (rl *RateLimiter) sweep(now time.Time) {
+	if rl.rate <= 0 {
+		return
+	}
+	buckets := make(map[string]*bucket, len(rl.buckets))
+	for k, b := range rl.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*rl.rate < rl.burst {
+			buckets[k] = b
+		}
+	}
+	rl.buckets = buckets
+	rl.nextSweep = now.Add(max(time.Duration(rl.burst/rl.rate*float64(time.Second)), time.Second))
+}
+
+// Wait takes a token for the call described by ctx. Unless the limiter fails fast, it blocks until a token is
+// available or ctx is done, returning ErrRateLimited if the wait would outlast the ctx deadline.
+func (rl *RateLimiter) Wait(ctx context.Context) error {
+	key := rl.key(ctx)
+
+	rl.mu.Lock()
+	b := rl.refill(key, time.Now())
+	if b.tokens >= 1 {
+		b.tokens--
+		rl.mu.Unlock()
+		return nil
+	}
+	if rl.failFast || rl.rate <= 0 {
+		rl.mu.Unlock()
+		return ErrRateLimited
+	}
+	delay := time.Duration((1 - b.tokens) / rl.rate * float64(time.Second))
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+		rl.mu.Unlock()
+		return ErrRateLimited
+	}
+	b.tokens--
+	rl.mu.Unlock()
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		rl.mu.Lock()
+		b = rl.refill(key, time.Now())
+		b.tokens = math.Min(rl.burst, b.tokens+1)
+		rl.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// Utilization reports the fraction of the bucket for ctx which is currently consumed. Values above 1 indicate
+// callers queued waiting for tokens.
+func (rl *RateLimiter) Utilization(ctx context.Context) float64 {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	if rl.burst <= 0 {
+		return 1
+	}
+	b := rl.refill(rl.key(ctx), time.Now())
+	return (rl.burst - b.tokens) / rl.burst
+}
+
+// Buckets reports the number of buckets currently tracked, one for each tenant which has called recently when the
+// limiter is per tenant.
+func (rl *RateLimiter) Buckets() int {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	return len(rl.buckets)
+}
@@ -0,0 +1,128 @@
+package requests_test
+
+import (
+	"context"
+	"errors"
+	"github.com/Chronicle20/atlas-rest/requests"
+	"github.com/Chronicle20/atlas-tenant"
+	"github.com/google/uuid"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterFailFast(t *testing.T) {
+	rl := requests.NewRateLimiter(1, 2, requests.SetRateLimitFailFast())
+
+	for i := 0; i < 2; i++ {
+		if err := rl.Wait(context.Background()); err != nil {
+			t.Fatalf("expected burst to be available, got [%v]", err)
+		}
+	}
+	if err := rl.Wait(context.Background()); !errors.Is(err, requests.ErrRateLimited) {
+		t.Fatalf("expected rate limited, got [%v]", err)
+	}
+	if u := rl.Utilization(context.Background()); u < 0.9 {
+		t.Fatalf("expected bucket to be consumed, got utilization [%f]", u)
+	}
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	rl := requests.NewRateLimiter(20, 1)
+
+	if u := rl.Utilization(context.Background()); u != 0 {
+		t.Fatalf("expected unused bucket, got utilization [%f]", u)
+	}
+	if err := rl.Wait(context.Background()); err != nil {
+		t.Fatal(err.Error())
+	}
+	start := time.Now()
+	if err := rl.Wait(context.Background()); err != nil {
+		t.Fatal(err.Error())
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Fatalf("expected to wait for a token, waited [%s]", elapsed)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if err := rl.Wait(ctx); !errors.Is(err, requests.ErrRateLimited) {
+		t.Fatalf("expected wait beyond deadline to be rate limited, got [%v]", err)
+	}
+}
+
+func TestRateLimiterCancelledWaitRefundsToken(t *testing.T) {
+	rl := requests.NewRateLimiter(0.001, 1)
+	if err := rl.Wait(context.Background()); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			cancel()
+		}()
+		if err := rl.Wait(ctx); !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected cancellation, got [%v]", err)
+		}
+	}
+	if u := rl.Utilization(context.Background()); u < 0.99 || u > 1.01 {
+		t.Fatalf("expected refunds to restore only the queued tokens, got utilization [%f]", u)
+	}
+}
+
+func TestRateLimiterPerTenant(t *testing.T) {
+	rl := requests.NewRateLimiter(1, 1, requests.SetRateLimitPerTenant(), requests.SetRateLimitFailFast())
+
+	a, err := tenant.Create(uuid.New(), "GMS", 83, 1)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	b, err := tenant.Create(uuid.New(), "GMS", 83, 1)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	actx := tenant.WithContext(context.Background(), a)
+	bctx := tenant.WithContext(context.Background(), b)
+
+	if err = rl.Wait(actx); err != nil {
+		t.Fatal(err.Error())
+	}
+	if err = rl.Wait(actx); !errors.Is(err, requests.ErrRateLimited) {
+		t.Fatalf("expected tenant bucket to be exhausted, got [%v]", err)
+	}
+	if err = rl.Wait(bctx); err != nil {
+		t.Fatalf("expected separate bucket for other tenant, got [%v]", err)
+	}
+	if u := rl.Utilization(context.Background()); u != 0 {
+		t.Fatalf("expected untenanted bucket to be unused, got utilization [%f]", u)
+	}
+}
+
+func TestRateLimiterEvictsIdleBuckets(t *testing.T) {
+	rl := requests.NewRateLimiter(100, 1, requests.SetRateLimitPerTenant())
+
+	var ctxs []context.Context
+	for i := 0; i < 3; i++ {
+		it, err := tenant.Create(uuid.New(), "GMS", 83, 1)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		ctx := tenant.WithContext(context.Background(), it)
+		if err = rl.Wait(ctx); err != nil {
+			t.Fatal(err.Error())
+		}
+		ctxs = append(ctxs, ctx)
+	}
+	if rl.Buckets() != 3 {
+		t.Fatalf("expected a bucket per tenant, got [%d]", rl.Buckets())
+	}
+
+	time.Sleep(1100 * time.Millisecond)
+	if err := rl.Wait(ctxs[0]); err != nil {
+		t.Fatal(err.Error())
+	}
+	if rl.Buckets() != 1 {
+		t.Fatalf("expected idle buckets to be evicted, got [%d]", rl.Buckets())
+	}
+}
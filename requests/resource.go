@@ -0,0 +1,118 @@
+package requests
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type ResourceIdentifier struct {
+	Type string `json:"type"`
+	Id   string `json:"id"`
+}
+
+type relationshipDocument struct {
+	Data []ResourceIdentifier `json:"data"`
+}
+
+// ResourceClient issues requests against a single JSON:API collection exposed by a domain, resolving the root url of
+// the domain with RootUrl when each request is made.
+type ResourceClient[A any] struct {
+	domain        string
+	path          string
+	configurators []Configurator
+}
+
+//goland:noinspection GoUnusedExportedFunction
+func NewResourceClient[A any](domain string, path string, configurators ...Configurator) ResourceClient[A] {
+	return ResourceClient[A]{domain: domain, path: strings.Trim(path, "/"), configurators: configurators}
+}
+
+func (rc ResourceClient[A]) Url() string {
+	return strings.TrimSuffix(RootUrl(rc.domain), "/") + "/" + rc.path
+}
+
+func (rc ResourceClient[A]) ResourceUrl(id string) string {
+	return rc.Url() + "/" + url.PathEscape(id)
+}
+
+func (rc ResourceClient[A]) RelatedUrl(id string, relationship string) string {
+	return rc.ResourceUrl(id) + "/" + url.PathEscape(relationship)
+}
+
+func (rc ResourceClient[A]) RelationshipUrl(id string, relationship string) string {
+	return rc.ResourceUrl(id) + "/relationships/" + url.PathEscape(relationship)
+}
+
+func (rc ResourceClient[A]) with(configurators []Configurator) []Configurator {
+	return append(append([]Configurator{}, rc.configurators...), configurators...)
+}
+
+func (rc ResourceClient[A]) Get(id string, configurators ...Configurator) Request[A] {
+	return MakeGetRequest[A](rc.ResourceUrl(id), rc.with(configurators)...)
+}
+
+func (rc ResourceClient[A]) List(query url.Values, configurators ...Configurator) Request[[]A] {
+	u := rc.Url()
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	return MakeGetRequest[[]A](u, rc.with(configurators)...)
+}
+
+func (rc ResourceClient[A]) Create(input interface{}, configurators ...Configurator) Request[A] {
+	return MakePostRequest[A](rc.Url(), input, rc.with(configurators)...)
+}
+
+func (rc ResourceClient[A]) Update(id string, input interface{}, configurators ...Configurator) Request[A] {
+	return MakePutRequest[A](rc.ResourceUrl(id), input, rc.with(configurators)...)
+}
+
+func (rc ResourceClient[A]) Patch(id string, input interface{}, configurators ...Configurator) Request[A] {
+	return MakePatchRequest[A](rc.ResourceUrl(id), input, rc.with(configurators)...)
+}
+
+func (rc ResourceClient[A]) Delete(id string, configurators ...Configurator) EmptyBodyRequest {
+	return MakeDeleteRequest(rc.ResourceUrl(id), rc.with(configurators)...)
+}
+
+func (rc ResourceClient[A]) GetRelationship(id string, relationship string, configurators ...Configurator) Request[[]ResourceIdentifier] {
+	return func(l logrus.FieldLogger, ctx context.Context) ([]ResourceIdentifier, error) {
+		cs := append(rc.with(configurators), SetCodec(JsonCodec{}))
+		doc, err := get[relationshipDocument](l, ctx)(rc.RelationshipUrl(id, relationship), cs...)
+		if err != nil {
+			return nil, err
+		}
+		return doc.Data, nil
+	}
+}
+
+func (rc ResourceClient[A]) AddToRelationship(id string, relationship string, identifiers []ResourceIdentifier, configurators ...Configurator) EmptyBodyRequest {
+	return rc.modifyRelationship(http.MethodPost, id, relationship, identifiers, configurators)
+}
+
+func (rc ResourceClient[A]) ReplaceRelationship(id string, relationship string, identifiers []ResourceIdentifier, configurators ...Configurator) EmptyBodyRequest {
+	return rc.modifyRelationship(http.MethodPatch, id, relationship, identifiers, configurators)
+}
+
+func (rc ResourceClient[A]) RemoveFromRelationship(id string, relationship string, identifiers []ResourceIdentifier, configurators ...Configurator) EmptyBodyRequest {
+	return rc.modifyRelationship(http.MethodDelete, id, relationship, identifiers, configurators)
+}
+
+func (rc ResourceClient[A]) modifyRelationship(method string, id string, relationship string, identifiers []ResourceIdentifier, configurators []Configurator) EmptyBodyRequest {
+	return func(l logrus.FieldLogger, ctx context.Context) error {
+		cs := append(rc.with(configurators), SetCodec(JsonCodec{}))
+		_, err := createOrUpdate[json.RawMessage](l, ctx)(method)(rc.RelationshipUrl(id, relationship), relationshipDocument{Data: identifiers}, cs...)
+		return err
+	}
+}
+
+// GetRelated retrieves the resources related to a resource of the client, which are generally of another type.
+//
+//goland:noinspection GoUnusedExportedFunction
+func GetRelated[A any, R any](rc ResourceClient[A], id string, relationship string, configurators ...Configurator) Request[R] {
+	return MakeGetRequest[R](rc.RelatedUrl(id, relationship), rc.with(configurators)...)
+}
@@ -0,0 +1,73 @@
+package requests_test
+
+import (
+	"context"
+	"github.com/Chronicle20/atlas-rest/requests"
+	"github.com/sirupsen/logrus/hooks/test"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestResourceClient(t *testing.T) {
+	l, _ := test.NewNullLogger()
+
+	var calls []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		calls = append(calls, r.Method+" "+r.URL.RequestURI()+" "+string(b))
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/characters":
+			_, _ = w.Write([]byte(characters))
+		case r.Method == http.MethodGet && r.URL.Path == "/api/characters/1":
+			_, _ = w.Write([]byte(`{"data":{"type":"characters","id":"1","attributes":{"name":"Atlas"}}}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/api/characters/1/relationships/items":
+			_, _ = w.Write([]byte(`{"data":[{"type":"items","id":"7"}]}`))
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer s.Close()
+
+	t.Setenv("CHARACTERS_SERVICE_URL", s.URL+"/api/")
+	rc := requests.NewResourceClient[character]("characters", "/characters")
+
+	c, err := rc.Get("1")(l, context.Background())
+	if err != nil || c.Name != "Atlas" {
+		t.Fatalf("unexpected resource [%v] [%v]", c, err)
+	}
+
+	cs, err := rc.List(url.Values{"name": []string{"Atlas"}})(l, context.Background())
+	if err != nil || len(cs) != 2 {
+		t.Fatalf("unexpected collection [%v] [%v]", cs, err)
+	}
+
+	ids, err := rc.GetRelationship("1", "items")(l, context.Background())
+	if err != nil || len(ids) != 1 || ids[0].Id != "7" {
+		t.Fatalf("unexpected relationship [%v] [%v]", ids, err)
+	}
+
+	err = rc.AddToRelationship("1", "items", []requests.ResourceIdentifier{{Type: "items", Id: "8"}})(l, context.Background())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	err = rc.Delete("1")(l, context.Background())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := []string{
+		"GET /api/characters/1 ",
+		"GET /api/characters?name=Atlas ",
+		"GET /api/characters/1/relationships/items ",
+		`POST /api/characters/1/relationships/items {"data":[{"type":"items","id":"8"}]}`,
+		"DELETE /api/characters/1 ",
+	}
+	if strings.Join(calls, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("unexpected calls\n%s", strings.Join(calls, "\n"))
+	}
+}
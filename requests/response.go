@@ -1,23 +1,46 @@
 package requests
 
 import (
-	"github.com/jtumidanski/api2go/jsonapi"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 )
 
-func processResponse[A any](r *http.Response) (A, error) {
+var ErrResponseTooLarge = errors.New("response too large")
+
+var ErrMalformedResponse = errors.New("malformed response")
+
+func processResponse[A any](c *configuration, r *http.Response) (A, error) {
 	var result A
-	body, err := io.ReadAll(r.Body)
+	defer r.Body.Close()
+
+	body, err := readBody(c, r)
 	if err != nil {
 		return result, err
 	}
-	defer r.Body.Close()
 
-	err = jsonapi.Unmarshal(body, &result)
+	err = c.codec.Unmarshal(body, &result)
 	if err != nil {
-		return result, err
+		return result, fmt.Errorf("%w: %w", ErrMalformedResponse, err)
 	}
 
 	return result, nil
 }
+
+func readBody(c *configuration, r *http.Response) ([]byte, error) {
+	if c.maxResponseSize <= 0 {
+		return io.ReadAll(r.Body)
+	}
+	if r.ContentLength > c.maxResponseSize {
+		return nil, ErrResponseTooLarge
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, c.maxResponseSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > c.maxResponseSize {
+		return nil, ErrResponseTooLarge
+	}
+	return body, nil
+}
@@ -0,0 +1,78 @@
+package requests
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"github.com/Chronicle20/atlas-rest/signature"
+	"github.com/google/uuid"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// SetSigningKey signs each request attempt with the supplied key, so that the receiving service can verify the origin
+// and integrity of the request.
+//
+//goland:noinspection GoUnusedExportedFunction
+func SetSigningKey(k signature.Key) Configurator {
+	return func(c *configuration) {
+		c.signingKey = &k
+	}
+}
+
+func sign(k signature.Key, req *http.Request) error {
+	bh, err := hashBody(req)
+	if err != nil {
+		return err
+	}
+
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	nonce := uuid.New().String()
+	canonical := signature.CanonicalWithBodyHash(req.Method, req.URL.RequestURI(), req.Header, ts, nonce, bh)
+
+	req.Header.Set(signature.TimestampHeader, ts)
+	req.Header.Set(signature.NonceHeader, nonce)
+	req.Header.Set(signature.Header, signature.Format(k.Id, signature.Sign(k, canonical)))
+	return nil
+}
+
+// hashBody streams a copy of the body through sha256 when it can be reopened. A body which cannot be reopened is
+// buffered so it may still be sent once hashed.
+func hashBody(req *http.Request) ([]byte, error) {
+	h := sha256.New()
+	if req.GetBody != nil {
+		rc, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		_, err = io.Copy(h, rc)
+		_ = rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		return h.Sum(nil), nil
+	}
+	if req.Body == nil || req.Body == http.NoBody {
+		return h.Sum(nil), nil
+	}
+
+	body := req.Body
+	pr, progress := body.(*progressReader)
+	if progress {
+		body = pr.ReadCloser
+	}
+	b, err := io.ReadAll(body)
+	_ = body.Close()
+	if err != nil {
+		return nil, err
+	}
+	var rc io.ReadCloser = io.NopCloser(bytes.NewReader(b))
+	if progress {
+		pr.ReadCloser = rc
+		rc = pr
+	}
+	req.Body = rc
+	h.Write(b)
+	return h.Sum(nil), nil
+}
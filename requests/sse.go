@@ -0,0 +1,296 @@
+package requests
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"io"
+	"iter"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const LastEventIdHeader = "Last-Event-ID"
+
+var ErrNotEventStream = errors.New("response is not an event stream")
+
+// ErrEventStreamIdle is returned when an event stream receives nothing, not even a heartbeat comment, within the idle
+// timeout. The stream is reconnected as though the connection had dropped.
+var ErrEventStreamIdle = errors.New("event stream idle")
+
+var errStopped = errors.New("event stream stopped")
+
+type Event struct {
+	Id    string
+	Event string
+	Data  string
+	Retry time.Duration
+}
+
+// EventHandler receives each event of a stream. Returning an error closes the stream without reconnecting.
+type EventHandler func(e Event) error
+
+type handlerError struct {
+	err error
+}
+
+func (e handlerError) Error() string {
+	return e.err.Error()
+}
+
+// SetReconnectDelay is the initial delay before an event stream is reconnected, until the server supplies one.
+//
+//goland:noinspection GoUnusedExportedFunction
+func SetReconnectDelay(delay time.Duration) Configurator {
+	return func(c *configuration) {
+		c.reconnectDelay = delay
+	}
+}
+
+// SetMaxReconnects bounds the consecutive attempts to reconnect an event stream which fail to receive an event. By
+// default, the stream is reconnected until the context is done.
+//
+//goland:noinspection GoUnusedExportedFunction
+func SetMaxReconnects(amount int) Configurator {
+	return func(c *configuration) {
+		c.maxReconnects = amount
+	}
+}
+
+// SetEventStreamIdleTimeout reconnects an event stream which receives no line, including heartbeat comments, within the
+// supplied timeout, detecting connections which stay open but have silently died. Time spent in the handler does not
+// count towards the timeout. By default, an idle stream is kept open.
+//
+//goland:noinspection GoUnusedExportedFunction
+func SetEventStreamIdleTimeout(timeout time.Duration) Configurator {
+	return func(c *configuration) {
+		c.eventStreamIdleTimeout = timeout
+	}
+}
+
+// SetLastEventId resumes an event stream after the supplied event id.
+//
+//goland:noinspection GoUnusedExportedFunction
+func SetLastEventId(id string) Configurator {
+	return func(c *configuration) {
+		c.lastEventId = id
+	}
+}
+
+// MakeEventStreamRequest consumes a Server-Sent Events stream, passing each event to the handler. When the connection
+// drops or fails with a retryable error, the stream is reconnected with the id of the last event received, so the
+// server may resume from it. The request completes when the server responds with 204, the handler fails, or ctx is
+// done.
+//
+//goland:noinspection GoUnusedExportedFunction
+func MakeEventStreamRequest(url string, handler EventHandler, configurators ...Configurator) EmptyBodyRequest {
+	return func(l logrus.FieldLogger, ctx context.Context) error {
+		c := newConfiguration(append([]Configurator{SetReconnectDelay(3 * time.Second)}, configurators...)...)
+
+		s := &eventStream{lastEventId: c.lastEventId, delay: c.reconnectDelay}
+		attempts := 0
+		for {
+			s.received = false
+			err := s.consume(l, ctx, c, url, handler)
+			if err == nil {
+				return nil
+			}
+			var herr handlerError
+			if errors.As(err, &herr) {
+				return herr.err
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if !errors.Is(err, io.EOF) && !errors.Is(err, ErrEventStreamIdle) && !c.retryable(err) {
+				l.WithError(err).Errorf("Unable to consume event stream from [%s].", c.policy().URL(url))
+				return err
+			}
+			if s.received {
+				attempts = 0
+			}
+			if c.maxReconnects > 0 && attempts >= c.maxReconnects {
+				l.WithError(err).Errorf("Unable to reconnect event stream from [%s].", c.policy().URL(url))
+				return err
+			}
+			attempts++
+
+			l.WithError(err).Debugf("Event stream from [%s] disconnected, reconnecting in [%s].", c.policy().URL(url), s.delay)
+			select {
+			case <-time.After(s.delay):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+// EventChannel consumes an event stream in the background. The event channel is closed when the stream completes,
+// after which the error channel yields the outcome.
+//
+//goland:noinspection GoUnusedExportedFunction
+func EventChannel(l logrus.FieldLogger, ctx context.Context, url string, configurators ...Configurator) (<-chan Event, <-chan error) {
+	events := make(chan Event)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		defer close(events)
+		errs <- MakeEventStreamRequest(url, func(e Event) error {
+			select {
+			case events <- e:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}, configurators...)(l, ctx)
+	}()
+	return events, errs
+}
+
+// Events consumes an event stream as an iterator. Stopping the iteration closes the stream, and a failure is yielded
+// as the final element.
+//
+//goland:noinspection GoUnusedExportedFunction
+func Events(l logrus.FieldLogger, ctx context.Context, url string, configurators ...Configurator) iter.Seq2[Event, error] {
+	return func(yield func(Event, error) bool) {
+		err := MakeEventStreamRequest(url, func(e Event) error {
+			if !yield(e, nil) {
+				return errStopped
+			}
+			return nil
+		}, configurators...)(l, ctx)
+		if err != nil && !errors.Is(err, errStopped) {
+			yield(Event{}, err)
+		}
+	}
+}
+
+type eventStream struct {
+	lastEventId string
+	delay       time.Duration
+	received    bool
+}
+
+// consume reads events from a single connection, returning nil only when the server indicates the stream is complete.
+// The connection is abandoned with ErrEventStreamIdle when the idle timeout passes without a line being read.
+func (s *eventStream) consume(l logrus.FieldLogger, ctx context.Context, c *configuration, url string, handler EventHandler) (err error) {
+	watch := func(active bool) {}
+	if c.eventStreamIdleTimeout > 0 {
+		var cancel context.CancelCauseFunc
+		ctx, cancel = context.WithCancelCause(ctx)
+		idle := time.AfterFunc(c.eventStreamIdleTimeout, func() {
+			cancel(ErrEventStreamIdle)
+		})
+		watch = func(active bool) {
+			if active {
+				idle.Reset(c.eventStreamIdleTimeout)
+			} else {
+				idle.Stop()
+			}
+		}
+		defer func() {
+			idle.Stop()
+			var herr handlerError
+			if err != nil && !errors.As(err, &herr) && errors.Is(context.Cause(ctx), ErrEventStreamIdle) {
+				err = ErrEventStreamIdle
+			}
+			cancel(nil)
+		}()
+	}
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+	req.Header.Set("Accept-Encoding", "identity")
+	c.decorate(ctx, req.Header)
+	if s.lastEventId != "" {
+		req.Header.Set(LastEventIdHeader, s.lastEventId)
+	}
+	req = req.WithContext(ctx)
+
+	r, err := do(l, ctx, c, req)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+
+	if r.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if r.StatusCode != http.StatusOK {
+		return statusError(http.MethodGet, r.StatusCode)
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "text/event-stream" {
+		return ErrNotEventStream
+	}
+
+	br := bufio.NewReader(r.Body)
+	e := Event{}
+	data := strings.Builder{}
+	for {
+		line, err := br.ReadString('\n')
+		watch(true)
+		if err != nil {
+			if err == io.EOF && line == "" {
+				return io.EOF
+			}
+			if err != io.EOF {
+				return err
+			}
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if c.maxResponseSize > 0 && int64(data.Len()+len(line)) > c.maxResponseSize {
+			return ErrResponseTooLarge
+		}
+
+		if line == "" {
+			if data.Len() > 0 {
+				e.Id = s.lastEventId
+				e.Data = strings.TrimSuffix(data.String(), "\n")
+				if e.Event == "" {
+					e.Event = "message"
+				}
+				s.received = true
+				watch(false)
+				if herr := handler(e); herr != nil {
+					return handlerError{err: herr}
+				}
+				watch(true)
+			}
+			e = Event{}
+			data.Reset()
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			e.Event = value
+		case "data":
+			data.WriteString(value)
+			data.WriteString("\n")
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				s.lastEventId = value
+			}
+		case "retry":
+			ms, err := strconv.Atoi(value)
+			if err == nil && ms >= 0 {
+				e.Retry = time.Duration(ms) * time.Millisecond
+				s.delay = e.Retry
+			}
+		}
+	}
+}
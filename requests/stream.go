@@ -0,0 +1,100 @@
+package requests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/jtumidanski/api2go/jsonapi"
+	"github.com/sirupsen/logrus"
+	"net/http"
+)
+
+var ErrMalformedDocument = errors.New("malformed document")
+
+type ElementHandler[A any] func(a A) error
+
+// streamResponse decodes the primary data of a JSON:API document one resource at a time, so only a single element is
+// held in memory. Top level members other than data, including included resources, are skipped. The configured
+// maximum response size is applied to each element.
+func streamResponse[A any](c *configuration, r *http.Response, handler ElementHandler[A]) error {
+	defer r.Body.Close()
+
+	dec := json.NewDecoder(r.Body)
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if tok != "data" {
+			var skip json.RawMessage
+			err = dec.Decode(&skip)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err = expectDelim(dec, '['); err != nil {
+			return err
+		}
+		for dec.More() {
+			var raw json.RawMessage
+			err = dec.Decode(&raw)
+			if err != nil {
+				return err
+			}
+			if c.maxResponseSize > 0 && int64(len(raw)) > c.maxResponseSize {
+				return ErrResponseTooLarge
+			}
+
+			var a A
+			doc := append(append([]byte(`{"data":`), raw...), '}')
+			err = jsonapi.Unmarshal(doc, &a)
+			if err != nil {
+				return fmt.Errorf("%w: %w", ErrMalformedDocument, err)
+			}
+			err = handler(a)
+			if err != nil {
+				return err
+			}
+		}
+		if err = expectDelim(dec, ']'); err != nil {
+			return err
+		}
+	}
+	return expectDelim(dec, '}')
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok != delim {
+		return fmt.Errorf("%w: expected [%s], found [%v]", ErrMalformedDocument, delim, tok)
+	}
+	return nil
+}
+
+// MakeStreamGetRequest issues a GET for a JSON:API collection and invokes the handler for each resource as it is
+// decoded, instead of buffering the whole response.
+//
+//goland:noinspection GoUnusedExportedFunction
+func MakeStreamGetRequest[A any](url string, handler ElementHandler[A], configurators ...Configurator) EmptyBodyRequest {
+	return func(l logrus.FieldLogger, ctx context.Context) error {
+		c := newConfiguration(configurators...)
+
+		r, err := fetch(l, ctx, c, url)
+		if err != nil {
+			return err
+		}
+		err = streamResponse[A](c, r, handler)
+		l.WithFields(logrus.Fields{"method": http.MethodGet, "status": r.Status, "path": c.policy().URL(url)}).Debugf("Printing request.")
+		return err
+	}
+}
@@ -0,0 +1,75 @@
+package requests_test
+
+import (
+	"context"
+	"errors"
+	"github.com/Chronicle20/atlas-rest/requests"
+	"github.com/sirupsen/logrus/hooks/test"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type character struct {
+	Id   string `json:"-"`
+	Name string `json:"name"`
+}
+
+func (c character) GetName() string {
+	return "characters"
+}
+
+func (c character) GetID() string {
+	return c.Id
+}
+
+func (c *character) SetID(id string) error {
+	c.Id = id
+	return nil
+}
+
+const characters = `{"data":[{"type":"characters","id":"1","attributes":{"name":"Atlas"}},{"type":"characters","id":"2","attributes":{"name":"Chronicle"}}],"meta":{"total":2}}`
+
+func TestStreamGetRequest(t *testing.T) {
+	l, _ := test.NewNullLogger()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(characters))
+	}))
+	defer s.Close()
+
+	var names []string
+	err := requests.MakeStreamGetRequest[character](s.URL, func(c character) error {
+		names = append(names, c.Id+":"+c.Name)
+		return nil
+	})(l, context.Background())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if strings.Join(names, ",") != "1:Atlas,2:Chronicle" {
+		t.Fatalf("unexpected elements [%v]", names)
+	}
+}
+
+func TestMaxResponseSize(t *testing.T) {
+	l, _ := test.NewNullLogger()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(characters))
+	}))
+	defer s.Close()
+
+	_, err := requests.MakeGetRequest[[]character](s.URL, requests.SetMaxResponseSize(16))(l, context.Background())
+	if !errors.Is(err, requests.ErrResponseTooLarge) {
+		t.Fatalf("expected response too large, got [%v]", err)
+	}
+
+	rs, err := requests.MakeGetRequest[[]character](s.URL, requests.SetMaxResponseSize(1024))(l, context.Background())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(rs) != 2 {
+		t.Fatalf("expected two elements, got [%d]", len(rs))
+	}
+}
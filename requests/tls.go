@@ -0,0 +1,82 @@
+package requests
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"runtime"
+	"sync"
+	"weak"
+)
+
+var clients sync.Map
+
+// NewClientTLSConfig creates a TLS configuration which trusts the certificate authorities in caFile and, when certFile
+// and keyFile are supplied, presents the key pair as the client certificate.
+//
+//goland:noinspection GoUnusedExportedFunction
+func NewClientTLSConfig(caFile string, certFile string, keyFile string) (*tls.Config, error) {
+	c := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if caFile != "" {
+		b, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("no certificates found in [%s]", caFile)
+		}
+		c.RootCAs = pool
+	}
+
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		c.Certificates = []tls.Certificate{cert}
+	}
+	return c, nil
+}
+
+// SetTLSConfig issues the request with a client using the supplied TLS configuration. Clients are shared between
+// requests using the same configuration so connections are reused, and are discarded once the configuration is no
+// longer referenced. The configuration should not be modified after it is first used.
+//
+//goland:noinspection GoUnusedExportedFunction
+func SetTLSConfig(tc *tls.Config) Configurator {
+	return func(c *configuration) {
+		c.tlsConfig = tc
+	}
+}
+
+func (c *configuration) client() *http.Client {
+	if c.tlsConfig == nil {
+		return http.DefaultClient
+	}
+	key := weak.Make(c.tlsConfig)
+	if hc, ok := clients.Load(key); ok {
+		return hc.(*http.Client)
+	}
+	// the transport holds a copy so that the cached client does not keep the configuration reachable.
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.TLSClientConfig = c.tlsConfig.Clone()
+	hc, loaded := clients.LoadOrStore(key, &http.Client{Transport: t})
+	if !loaded {
+		runtime.AddCleanup(c.tlsConfig, evictClient, clientEntry{key: key, transport: t})
+	}
+	return hc.(*http.Client)
+}
+
+type clientEntry struct {
+	key       weak.Pointer[tls.Config]
+	transport *http.Transport
+}
+
+func evictClient(e clientEntry) {
+	clients.Delete(e.key)
+	e.transport.CloseIdleConnections()
+}
@@ -0,0 +1,170 @@
+package requests
+
+import (
+	"context"
+	"fmt"
+	"github.com/Chronicle20/atlas-model/model"
+	"github.com/sirupsen/logrus"
+	"strings"
+	"sync"
+)
+
+// ElementError is the failure to transform the element at Index of a slice.
+type ElementError struct {
+	Index int
+	Err   error
+}
+
+func (e ElementError) Error() string {
+	return fmt.Sprintf("element [%d]: %s", e.Index, e.Err.Error())
+}
+
+func (e ElementError) Unwrap() error {
+	return e.Err
+}
+
+// TransformErrors collects the elements of a slice which failed to transform, ordered by index.
+type TransformErrors []ElementError
+
+func (e TransformErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, ee := range e {
+		msgs = append(msgs, ee.Error())
+	}
+	return fmt.Sprintf("unable to transform [%d] elements: %s", len(e), strings.Join(msgs, "; "))
+}
+
+func (e TransformErrors) Unwrap() []error {
+	errs := make([]error, 0, len(e))
+	for _, ee := range e {
+		errs = append(errs, ee)
+	}
+	return errs
+}
+
+type transformConfiguration struct {
+	parallelism   int
+	collectErrors bool
+}
+
+type TransformConfigurator func(c *transformConfiguration)
+
+// SetTransformParallelism bounds the number of elements transformed concurrently.
+//
+//goland:noinspection GoUnusedExportedFunction
+func SetTransformParallelism(parallelism int) TransformConfigurator {
+	return func(c *transformConfiguration) {
+		c.parallelism = parallelism
+	}
+}
+
+// CollectTransformErrors transforms every element even when some fail. The provider then yields the elements which
+// transformed successfully along with TransformErrors describing those which did not.
+//
+//goland:noinspection GoUnusedExportedFunction
+func CollectTransformErrors() TransformConfigurator {
+	return func(c *transformConfiguration) {
+		c.collectErrors = true
+	}
+}
+
+// ParallelSliceProvider behaves as SliceProvider, but transforms elements concurrently, preserving their order. By
+// default, the first transform error fails the slice and no further elements are started.
+//
+//goland:noinspection GoUnusedExportedFunction
+func ParallelSliceProvider[A any, M any](l logrus.FieldLogger, ctx context.Context, configurators ...TransformConfigurator) func(r Request[[]A], t model.Transformer[A, M], filters []model.Filter[M]) model.Provider[[]M] {
+	c := &transformConfiguration{parallelism: 10}
+	for _, configurator := range configurators {
+		configurator(c)
+	}
+	if c.parallelism < 1 {
+		c.parallelism = 1
+	}
+
+	return func(r Request[[]A], t model.Transformer[A, M], filters []model.Filter[M]) model.Provider[[]M] {
+		resp, err := r(l, ctx)
+		if err != nil {
+			return model.ErrorProvider[[]M](err)
+		}
+		ms, err := transform(ctx, c, resp, t)
+		if err != nil && !c.collectErrors {
+			return model.ErrorProvider[[]M](err)
+		}
+		return func() ([]M, error) {
+			return filter(ms, filters), err
+		}
+	}
+}
+
+func transform[A any, M any](ctx context.Context, c *transformConfiguration, as []A, t model.Transformer[A, M]) ([]M, error) {
+	results := make([]M, len(as))
+	failed := make([]error, len(as))
+
+	tctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	sem := make(chan struct{}, c.parallelism)
+	var wg sync.WaitGroup
+	for i, a := range as {
+		select {
+		case sem <- struct{}{}:
+		case <-tctx.Done():
+		}
+		if tctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(i int, a A) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			m, err := t(a)
+			if err != nil {
+				failed[i] = err
+				if !c.collectErrors {
+					cancel()
+				}
+				return
+			}
+			results[i] = m
+		}(i, a)
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var errs TransformErrors
+	ms := make([]M, 0, len(as))
+	for i := range as {
+		if failed[i] != nil {
+			if !c.collectErrors {
+				return nil, ElementError{Index: i, Err: failed[i]}
+			}
+			errs = append(errs, ElementError{Index: i, Err: failed[i]})
+			continue
+		}
+		ms = append(ms, results[i])
+	}
+	if len(errs) > 0 {
+		return ms, errs
+	}
+	return ms, nil
+}
+
+func filter[M any](ms []M, filters []model.Filter[M]) []M {
+	results := make([]M, 0, len(ms))
+	for _, m := range ms {
+		ok := true
+		for _, f := range filters {
+			if !f(m) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			results = append(results, m)
+		}
+	}
+	return results
+}
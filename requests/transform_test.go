@@ -0,0 +1,56 @@
+package requests_test
+
+import (
+	"context"
+	"errors"
+	"github.com/Chronicle20/atlas-model/model"
+	"github.com/Chronicle20/atlas-rest/requests"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParallelSliceProvider(t *testing.T) {
+	l, _ := test.NewNullLogger()
+
+	r := func(l logrus.FieldLogger, ctx context.Context) ([]int, error) {
+		return []int{1, 2, 3, 4, 5, 6}, nil
+	}
+	var inFlight, peak int32
+	errOdd := errors.New("odd")
+	half := func(v int) (int, error) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(time.Duration(6-v) * time.Millisecond)
+		if v%2 == 1 {
+			return 0, errOdd
+		}
+		return v / 2, nil
+	}
+
+	ms, err := requests.ParallelSliceProvider[int, int](l, context.Background(), requests.SetTransformParallelism(2), requests.CollectTransformErrors())(r, half, []model.Filter[int]{})()
+	if len(ms) != 3 || ms[0] != 1 || ms[1] != 2 || ms[2] != 3 {
+		t.Fatalf("unexpected ordered results [%v]", ms)
+	}
+	var terrs requests.TransformErrors
+	if !errors.As(err, &terrs) || len(terrs) != 3 || terrs[0].Index != 0 || !errors.Is(err, errOdd) {
+		t.Fatalf("unexpected collected errors [%v]", err)
+	}
+	if peak > 2 {
+		t.Fatalf("expected at most [2] concurrent transforms, observed [%d]", peak)
+	}
+
+	_, err = requests.ParallelSliceProvider[int, int](l, context.Background())(r, half, []model.Filter[int]{})()
+	var ee requests.ElementError
+	if !errors.As(err, &ee) || !errors.Is(err, errOdd) {
+		t.Fatalf("expected element error, got [%v]", err)
+	}
+}
@@ -0,0 +1,121 @@
+package requests
+
+import (
+	"context"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strings"
+)
+
+// MakeBinaryRequest issues a request with a raw body of the supplied content type, decoding the response with the
+// configured codec.
+//
+//goland:noinspection GoUnusedExportedFunction
+func MakeBinaryRequest[A any](method string, url string, contentType string, source BodySource, configurators ...Configurator) Request[A] {
+	return func(l logrus.FieldLogger, ctx context.Context) (A, error) {
+		c := newConfiguration(configurators...)
+		return exchange[A](l, ctx, c, method, url, contentType, "", source, contentType)
+	}
+}
+
+type MultipartPart struct {
+	Field       string
+	FileName    string
+	ContentType string
+	Value       string
+	Source      BodySource
+}
+
+//goland:noinspection GoUnusedExportedFunction
+func MultipartField(name string, value string) MultipartPart {
+	return MultipartPart{Field: name, Value: value}
+}
+
+//goland:noinspection GoUnusedExportedFunction
+func MultipartFile(field string, fileName string, contentType string, source BodySource) MultipartPart {
+	return MultipartPart{Field: field, FileName: fileName, ContentType: contentType, Source: source}
+}
+
+// MakeMultipartRequest issues a POST with a multipart/form-data body. Parts are streamed from their sources rather than
+// buffered, and the request is retried only when every source may be reopened.
+//
+//goland:noinspection GoUnusedExportedFunction
+func MakeMultipartRequest[A any](url string, parts []MultipartPart, configurators ...Configurator) Request[A] {
+	return func(l logrus.FieldLogger, ctx context.Context) (A, error) {
+		c := newConfiguration(configurators...)
+		source, contentType := multipartSource(parts)
+
+		fields := make([]string, 0, len(parts))
+		for _, p := range parts {
+			fields = append(fields, p.Field)
+		}
+		return exchange[A](l, ctx, c, http.MethodPost, url, contentType, "", source, fields)
+	}
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func multipartSource(parts []MultipartPart) (BodySource, string) {
+	boundary := multipart.NewWriter(io.Discard).Boundary()
+
+	reopenable := true
+	for _, p := range parts {
+		if p.Source.open != nil && !p.Source.reopenable {
+			reopenable = false
+		}
+	}
+
+	open := func() (io.ReadCloser, int64, error) {
+		pr, pw := io.Pipe()
+		go func() {
+			mw := multipart.NewWriter(pw)
+			_ = mw.SetBoundary(boundary)
+			err := writeParts(mw, parts)
+			if err == nil {
+				err = mw.Close()
+			}
+			_ = pw.CloseWithError(err)
+		}()
+		return pr, -1, nil
+	}
+	return BodySource{open: open, reopenable: reopenable}, "multipart/form-data; boundary=" + boundary
+}
+
+func writeParts(mw *multipart.Writer, parts []MultipartPart) error {
+	for _, p := range parts {
+		if p.Source.open == nil {
+			err := mw.WriteField(p.Field, p.Value)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, quoteEscaper.Replace(p.Field), quoteEscaper.Replace(p.FileName)))
+		contentType := p.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		h.Set("Content-Type", contentType)
+		w, err := mw.CreatePart(h)
+		if err != nil {
+			return err
+		}
+
+		rc, _, err := p.Source.open()
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(w, rc)
+		_ = rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package requests_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/Chronicle20/atlas-rest/requests"
+	"github.com/sirupsen/logrus/hooks/test"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMultipartUpload(t *testing.T) {
+	l, _ := test.NewNullLogger()
+
+	var received map[string]string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := r.ParseMultipartForm(1 << 20)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received = map[string]string{"version": r.FormValue("version")}
+		f, fh, err := r.FormFile("asset")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		b, _ := io.ReadAll(f)
+		received["asset"] = fh.Filename + ":" + string(b)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer s.Close()
+
+	path := filepath.Join(t.TempDir(), "Character.wz")
+	err := os.WriteFile(path, []byte("wz-content"), 0600)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	parts := []requests.MultipartPart{
+		requests.MultipartField("version", "83"),
+		requests.MultipartFile("asset", "Character.wz", "application/octet-stream", requests.FileSource(path)),
+	}
+	_, err = requests.MakeMultipartRequest[character](s.URL, parts)(l, context.Background())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if received["version"] != "83" || received["asset"] != "Character.wz:wz-content" {
+		t.Fatalf("unexpected upload [%v]", received)
+	}
+}
+
+func TestDownload(t *testing.T) {
+	l, _ := test.NewNullLogger()
+
+	content := strings.Repeat("export", 1000)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != "*/*" {
+			w.WriteHeader(http.StatusNotAcceptable)
+			return
+		}
+		_, _ = w.Write([]byte(content))
+	}))
+	defer s.Close()
+
+	var buf bytes.Buffer
+	var transferred int64
+	n, err := requests.MakeDownloadRequest(s.URL, &buf, requests.SetProgress(func(t int64, _ int64) { transferred = t }))(l, context.Background())
+	if err != nil || n != int64(len(content)) || buf.String() != content || transferred != n {
+		t.Fatalf("unexpected download of [%d] bytes, reported [%d] [%v]", n, transferred, err)
+	}
+
+	buf.Reset()
+	n, err = requests.MakeDownloadRequest(s.URL, &buf, requests.SetMaxResponseSize(100))(l, context.Background())
+	if !errors.Is(err, requests.ErrResponseTooLarge) || buf.Len() > 100 {
+		t.Fatalf("expected size limit to be enforced, wrote [%d] [%v]", buf.Len(), err)
+	}
+}
+
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestUploadBodyClosedWhenNotSent(t *testing.T) {
+	l, _ := test.NewNullLogger()
+
+	rl := requests.NewRateLimiter(0.001, 1, requests.SetRateLimitFailFast())
+	_ = rl.Wait(context.Background())
+
+	body := &closeRecorder{Reader: strings.NewReader("wz-content")}
+	_, err := requests.MakeBinaryRequest[character](http.MethodPut, "http://localhost", "application/octet-stream", requests.ReaderSource(body), requests.SetRateLimiter(rl))(l, context.Background())
+	if !errors.Is(err, requests.ErrRateLimited) || !body.closed {
+		t.Fatalf("expected body to be closed when the request is rejected, got [%v] closed [%t]", err, body.closed)
+	}
+}
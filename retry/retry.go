@@ -2,22 +2,45 @@ package retry
 
 import (
 	"errors"
+	"fmt"
 	"time"
 )
 
+var ErrMaxRetries = errors.New("max retry reached")
+
 type TryFunc func(attempt int) (retry bool, err error)
 
+// Delayer is implemented by errors which request a delay before the next attempt, such as those carrying Retry-After.
+type Delayer interface {
+	RetryAfter() time.Duration
+}
+
+// Try invokes fn until it succeeds, declines to retry, or has been attempted retries times, sleeping between attempts
+// for a second, or for the delay requested by an error implementing Delayer. The error of an attempt which declines to
+// retry is returned rather than treated as success, and exhausting the attempts returns ErrMaxRetries wrapping the
+// error of the last attempt.
 func Try(fn TryFunc, retries int) error {
 	attempt := 1
 	for {
 		cont, err := fn(attempt)
-		if !cont || err == nil {
+		if err == nil {
 			return nil
 		}
+		if !cont {
+			return err
+		}
 		attempt++
 		if attempt > retries {
-			return errors.New("max retry reached")
+			return fmt.Errorf("%w: %w", ErrMaxRetries, err)
 		}
-		time.Sleep(1 * time.Second)
+		time.Sleep(delay(err))
+	}
+}
+
+func delay(err error) time.Duration {
+	var d Delayer
+	if errors.As(err, &d) && d.RetryAfter() > 0 {
+		return d.RetryAfter()
 	}
-}
\ No newline at end of file
+	return 1 * time.Second
+}
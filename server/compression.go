@@ -0,0 +1,297 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"github.com/klauspost/compress/zstd"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	EncodingGzip = "gzip"
+	EncodingZstd = "zstd"
+)
+
+// encoders and decoders are pooled, and limited to a single goroutine each, as by default every zstd instance starts
+// workers and allocates windows sized for the number of CPUs.
+var (
+	gzipWriters = sync.Pool{New: func() interface{} {
+		return gzip.NewWriter(nil)
+	}}
+	gzipReaders = sync.Pool{New: func() interface{} {
+		return new(gzip.Reader)
+	}}
+	zstdEncoders = sync.Pool{New: func() interface{} {
+		zw, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+		return zw
+	}}
+	zstdDecoders = sync.Pool{New: func() interface{} {
+		zr, _ := zstd.NewReader(nil, zstd.WithDecoderConcurrency(1))
+		return zr
+	}}
+)
+
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// pooledEncoder returns the encoder to its pool once closed.
+type pooledEncoder struct {
+	encoder
+	release func()
+}
+
+func (e pooledEncoder) Close() error {
+	err := e.encoder.Close()
+	e.release()
+	return err
+}
+
+func newEncoder(encoding string, w io.Writer) encoder {
+	switch encoding {
+	case EncodingGzip:
+		gw := gzipWriters.Get().(*gzip.Writer)
+		gw.Reset(w)
+		return pooledEncoder{encoder: gw, release: func() { gzipWriters.Put(gw) }}
+	case EncodingZstd:
+		zw := zstdEncoders.Get().(*zstd.Encoder)
+		zw.Reset(w)
+		return pooledEncoder{encoder: zw, release: func() { zstdEncoders.Put(zw) }}
+	}
+	return nil
+}
+
+type CompressionConfigurator func(c *compressionConfig)
+
+type compressionConfig struct {
+	maxDecompressedSize int64
+}
+
+// SetMaxDecompressedSize bounds the decoded size of a compressed request body, beyond which reading it fails. By
+// default, the decoded body is limited to 10 MiB.
+//
+//goland:noinspection GoUnusedExportedFunction
+func SetMaxDecompressedSize(size int64) CompressionConfigurator {
+	return func(c *compressionConfig) {
+		c.maxDecompressedSize = size
+	}
+}
+
+// negotiateEncoding selects the preferred supported encoding from an Accept-Encoding header, favouring zstd over gzip
+// when both are equally acceptable.
+func negotiateEncoding(header string) string {
+	best := ""
+	bestQ := 0.0
+	for _, part := range strings.Split(header, ",") {
+		bits := strings.Split(strings.TrimSpace(part), ";")
+		name := strings.ToLower(strings.TrimSpace(bits[0]))
+		if name != EncodingGzip && name != EncodingZstd {
+			continue
+		}
+		q := 1.0
+		for _, param := range bits[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				v, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64)
+				if err == nil {
+					q = v
+				}
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		if q > bestQ || (q == bestQ && name == EncodingZstd) {
+			best = name
+			bestQ = q
+		}
+	}
+	return best
+}
+
+type compressingResponseWriter struct {
+	http.ResponseWriter
+	encoding   string
+	minSize    int
+	statusCode int
+	buf        bytes.Buffer
+	encoder    encoder
+	started    bool
+}
+
+func (w *compressingResponseWriter) WriteHeader(statusCode int) {
+	if w.statusCode == 0 {
+		w.statusCode = statusCode
+	}
+}
+
+func (w *compressingResponseWriter) Write(b []byte) (int, error) {
+	if w.statusCode == 0 {
+		w.statusCode = http.StatusOK
+	}
+	if w.encoder != nil {
+		return w.encoder.Write(b)
+	}
+	if w.started {
+		return w.ResponseWriter.Write(b)
+	}
+	w.buf.Write(b)
+	if w.buf.Len() < w.minSize {
+		return len(b), nil
+	}
+	err := w.startEncoding()
+	if err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (w *compressingResponseWriter) startEncoding() error {
+	w.started = true
+	h := w.Header()
+	if h.Get("Content-Encoding") != "" || w.statusCode == http.StatusNoContent || w.statusCode == http.StatusNotModified {
+		w.ResponseWriter.WriteHeader(w.statusCode)
+		_, err := w.ResponseWriter.Write(w.buf.Bytes())
+		return err
+	}
+
+	w.encoder = newEncoder(w.encoding, w.ResponseWriter)
+	h.Set("Content-Encoding", w.encoding)
+	h.Del("Content-Length")
+	w.ResponseWriter.WriteHeader(w.statusCode)
+	_, err := w.encoder.Write(w.buf.Bytes())
+	return err
+}
+
+// Flush sends what has been written so far. Responses flushed before reaching the minimum size, such as event
+// streams, are sent uncompressed.
+func (w *compressingResponseWriter) Flush() {
+	if !w.started {
+		w.started = true
+		if w.statusCode == 0 {
+			w.statusCode = http.StatusOK
+		}
+		w.ResponseWriter.WriteHeader(w.statusCode)
+		if w.buf.Len() > 0 {
+			_, _ = w.ResponseWriter.Write(w.buf.Bytes())
+		}
+	}
+	if w.encoder != nil {
+		_ = w.encoder.Flush()
+	}
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *compressingResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *compressingResponseWriter) finish() error {
+	if w.encoder != nil {
+		return w.encoder.Close()
+	}
+	if w.started {
+		return nil
+	}
+	w.started = true
+	if w.statusCode != 0 {
+		w.ResponseWriter.WriteHeader(w.statusCode)
+	}
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	_, err := w.ResponseWriter.Write(w.buf.Bytes())
+	return err
+}
+
+// decompressRequest replaces the body of the request with its decoded form, limited to maxSize bytes. The returned
+// function releases the decoder once the request has been handled.
+func decompressRequest(w http.ResponseWriter, r *http.Request, maxSize int64) (func(), error) {
+	encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+	var body io.ReadCloser
+	release := func() {}
+	switch encoding {
+	case "", "identity":
+		return release, nil
+	case EncodingGzip:
+		gr := gzipReaders.Get().(*gzip.Reader)
+		err := gr.Reset(r.Body)
+		if err != nil {
+			gzipReaders.Put(gr)
+			return release, err
+		}
+		body = gr
+		release = func() {
+			gzipReaders.Put(gr)
+		}
+	case EncodingZstd:
+		zr := zstdDecoders.Get().(*zstd.Decoder)
+		err := zr.Reset(r.Body)
+		if err != nil {
+			zstdDecoders.Put(zr)
+			return release, err
+		}
+		body = io.NopCloser(zr)
+		release = func() {
+			_ = zr.Reset(nil)
+			zstdDecoders.Put(zr)
+		}
+	default:
+		return release, http.ErrNotSupported
+	}
+	r.Body = http.MaxBytesReader(w, body, maxSize)
+	r.Header.Del("Content-Encoding")
+	r.Header.Del("Content-Length")
+	r.ContentLength = -1
+	return release, nil
+}
+
+// CompressionMiddleware decodes gzip or zstd encoded request bodies, and compresses responses of at least minSize
+// bytes using the encoding negotiated from Accept-Encoding. Every response varies by Accept-Encoding, whether or not
+// it ends up compressed, so that caches do not serve one encoding to clients which negotiated another. Reading a
+// decoded body beyond its limit fails with *http.MaxBytesError.
+//
+//goland:noinspection GoUnusedExportedFunction
+func CompressionMiddleware(l logrus.FieldLogger, minSize int, configurators ...CompressionConfigurator) func(next http.Handler) http.Handler {
+	c := &compressionConfig{maxDecompressedSize: 10 << 20}
+	for _, configurator := range configurators {
+		configurator(c)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			release, err := decompressRequest(w, r, c.maxDecompressedSize)
+			defer release()
+			if err != nil {
+				l.WithError(err).Errorf("Unable to decode request body with Content-Encoding [%s].", r.Header.Get("Content-Encoding"))
+				if errors.Is(err, http.ErrNotSupported) {
+					w.WriteHeader(http.StatusUnsupportedMediaType)
+					return
+				}
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			w.Header().Add("Vary", "Accept-Encoding")
+			encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+			if encoding == "" || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressingResponseWriter{ResponseWriter: w, encoding: encoding, minSize: minSize}
+			next.ServeHTTP(cw, r)
+			err = cw.finish()
+			if err != nil {
+				l.WithError(err).Errorf("Unable to write response.")
+			}
+		})
+	}
+}
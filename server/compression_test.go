@@ -0,0 +1,207 @@
+package server_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"github.com/Chronicle20/atlas-rest/requests"
+	"github.com/Chronicle20/atlas-rest/server"
+	"github.com/klauspost/compress/zstd"
+	"github.com/sirupsen/logrus/hooks/test"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type character struct {
+	Id   string `json:"-"`
+	Name string `json:"name"`
+}
+
+func (c character) GetName() string {
+	return "characters"
+}
+
+func (c character) GetID() string {
+	return c.Id
+}
+
+func (c *character) SetID(id string) error {
+	c.Id = id
+	return nil
+}
+
+func TestCompressedResponse(t *testing.T) {
+	l, _ := test.NewNullLogger()
+
+	name := strings.Repeat("a", 2048)
+	for _, encoding := range []string{server.EncodingGzip, server.EncodingZstd} {
+		var received string
+		s := httptest.NewServer(server.CompressionMiddleware(l, 1024)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r.Header.Get("Accept-Encoding")
+			_, _ = w.Write([]byte(`{"data":{"type":"characters","id":"1","attributes":{"name":"` + name + `"}}}`))
+		})))
+
+		c, err := requests.MakeGetRequest[character](s.URL, requests.SetAcceptEncoding(encoding))(l, context.Background())
+		s.Close()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if received != encoding {
+			t.Fatalf("expected Accept-Encoding [%s], got [%s]", encoding, received)
+		}
+		if c.Name != name {
+			t.Fatalf("unexpected response for encoding [%s]", encoding)
+		}
+	}
+}
+
+func TestCompressionThreshold(t *testing.T) {
+	l, _ := test.NewNullLogger()
+
+	h := server.CompressionMiddleware(l, 1024)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("small"))
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated || w.Header().Get("Content-Encoding") != "" || w.Body.String() != "small" {
+		t.Fatalf("expected uncompressed response, got [%d] [%s]", w.Code, w.Header().Get("Content-Encoding"))
+	}
+	if vary := w.Header().Values("Vary"); len(vary) != 1 || vary[0] != "Accept-Encoding" {
+		t.Fatalf("expected uncompressed response to vary by Accept-Encoding, got [%v]", vary)
+	}
+}
+
+func TestCompressedRequest(t *testing.T) {
+	l, _ := test.NewNullLogger()
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	_, _ = gw.Write([]byte("payload"))
+	_ = gw.Close()
+
+	var body string
+	h := server.CompressionMiddleware(l, 1024)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/", &buf)
+	req.Header.Set("Content-Encoding", "gzip")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if body != "payload" {
+		t.Fatalf("unexpected request body [%s]", body)
+	}
+}
+
+func TestDecompressedRequestLimit(t *testing.T) {
+	l, _ := test.NewNullLogger()
+
+	for _, encoding := range []string{server.EncodingGzip, server.EncodingZstd} {
+		var buf bytes.Buffer
+		var ew io.WriteCloser
+		if encoding == server.EncodingGzip {
+			ew = gzip.NewWriter(&buf)
+		} else {
+			ew, _ = zstd.NewWriter(&buf)
+		}
+		_, _ = ew.Write(bytes.Repeat([]byte{0}, 1<<20))
+		_ = ew.Close()
+
+		var readErr error
+		h := server.CompressionMiddleware(l, 1024, server.SetMaxDecompressedSize(1024))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, readErr = io.ReadAll(r.Body)
+		}))
+
+		req := httptest.NewRequest(http.MethodPost, "/", &buf)
+		req.Header.Set("Content-Encoding", encoding)
+		h.ServeHTTP(httptest.NewRecorder(), req)
+		var mbe *http.MaxBytesError
+		if !errors.As(readErr, &mbe) {
+			t.Fatalf("expected [%s] body beyond limit to fail, got [%v]", encoding, readErr)
+		}
+	}
+}
+
+func TestConcurrentCompression(t *testing.T) {
+	l, _ := test.NewNullLogger()
+
+	h := server.CompressionMiddleware(l, 16)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		_, _ = w.Write(b)
+	}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			payload := strings.Repeat(strconv.Itoa(i), 512)
+
+			var buf bytes.Buffer
+			encoding := server.EncodingZstd
+			if i%2 == 0 {
+				encoding = server.EncodingGzip
+				gw := gzip.NewWriter(&buf)
+				_, _ = gw.Write([]byte(payload))
+				_ = gw.Close()
+			} else {
+				zw, _ := zstd.NewWriter(&buf)
+				_, _ = zw.Write([]byte(payload))
+				_ = zw.Close()
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/", &buf)
+			req.Header.Set("Content-Encoding", encoding)
+			req.Header.Set("Accept-Encoding", server.EncodingZstd)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			zr, err := zstd.NewReader(w.Body)
+			if err != nil {
+				t.Error(err.Error())
+				return
+			}
+			defer zr.Close()
+			b, err := io.ReadAll(zr)
+			if err != nil || string(b) != payload {
+				t.Errorf("unexpected round trip of [%d] bytes [%v]", len(b), err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestConcurrentClientCompression(t *testing.T) {
+	l, _ := test.NewNullLogger()
+
+	s := httptest.NewServer(server.CompressionMiddleware(l, 16)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write(b)
+	})))
+	defer s.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			name := strings.Repeat(strconv.Itoa(i), 512)
+			c, err := requests.MakePostRequest[character](s.URL, character{Id: "1", Name: name}, requests.SetRequestCompression(requests.EncodingZstd, 16), requests.SetAcceptEncoding(requests.EncodingZstd))(l, context.Background())
+			if err != nil || c.Name != name {
+				t.Errorf("unexpected round trip of [%d] bytes [%v]", len(c.Name), err)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,139 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"strings"
+)
+
+const (
+	ETagHeader    = "ETag"
+	IfMatchHeader = "If-Match"
+)
+
+var (
+	ErrPreconditionFailed   = errors.New("precondition failed")
+	ErrPreconditionRequired = errors.New("precondition required")
+)
+
+// ComputeETag derives a strong entity tag from the JSON representation of a resource.
+//
+//goland:noinspection GoUnusedExportedFunction
+func ComputeETag(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return "\"" + hex.EncodeToString(sum[:16]) + "\"", nil
+}
+
+// SetETag computes the entity tag of a resource and sets it on the response.
+//
+//goland:noinspection GoUnusedExportedFunction
+func SetETag(w http.ResponseWriter, v interface{}) error {
+	etag, err := ComputeETag(v)
+	if err != nil {
+		return err
+	}
+	w.Header().Set(ETagHeader, etag)
+	return nil
+}
+
+// VersionProvider returns the current entity tag of the resource targeted by the request, or an empty string when
+// the resource does not exist.
+type VersionProvider func(r *http.Request) (string, error)
+
+// CheckIfMatch evaluates the If-Match condition of the request against the current version of the resource, failing
+// with ErrPreconditionFailed when it does not match, or ErrPreconditionRequired when required and not supplied. A
+// handler should call it while holding the lock which guards the resource, and apply the change only when it succeeds,
+// so that the check and the change are atomic.
+//
+//goland:noinspection GoUnusedExportedFunction
+func CheckIfMatch(r *http.Request, current string, required bool) error {
+	condition := r.Header.Get(IfMatchHeader)
+	if condition == "" {
+		if required {
+			return ErrPreconditionRequired
+		}
+		return nil
+	}
+	if !ifMatch(condition, current) {
+		return ErrPreconditionFailed
+	}
+	return nil
+}
+
+// WritePreconditionError responds to a request which failed CheckIfMatch, supplying the current version of the
+// resource when the condition did not match.
+//
+//goland:noinspection GoUnusedExportedFunction
+func WritePreconditionError(w http.ResponseWriter, err error, current string) {
+	if errors.Is(err, ErrPreconditionRequired) {
+		w.WriteHeader(http.StatusPreconditionRequired)
+		return
+	}
+	if current != "" {
+		w.Header().Set(ETagHeader, current)
+	}
+	w.WriteHeader(http.StatusPreconditionFailed)
+}
+
+// IfMatchMiddleware rejects PUT, PATCH and DELETE requests whose If-Match header does not match the current version
+// of the resource with 412. When required is set, mutating requests without If-Match are rejected with 428. The
+// version is read before the handler runs, so a concurrent change may land between the check and the handler; handlers
+// which must apply changes atomically should use CheckIfMatch under their own lock instead.
+//
+//goland:noinspection GoUnusedExportedFunction
+func IfMatchMiddleware(l logrus.FieldLogger, version VersionProvider, required bool) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPut && r.Method != http.MethodPatch && r.Method != http.MethodDelete {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if r.Header.Get(IfMatchHeader) == "" {
+				if required {
+					l.Debugf("Request to [%s] did not supply %s.", r.URL.Path, IfMatchHeader)
+					WritePreconditionError(w, ErrPreconditionRequired, "")
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			current, err := version(r)
+			if err != nil {
+				l.WithError(err).Errorf("Unable to determine version of resource at [%s].", r.URL.Path)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			err = CheckIfMatch(r, current, required)
+			if err != nil {
+				l.Debugf("Request to [%s] supplied %s [%s] but resource is at [%s].", r.URL.Path, IfMatchHeader, r.Header.Get(IfMatchHeader), current)
+				WritePreconditionError(w, err, current)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ifMatch evaluates an If-Match condition using strong comparison, so weak entity tags never match.
+func ifMatch(condition string, current string) bool {
+	if current == "" || strings.HasPrefix(current, "W/") {
+		return false
+	}
+	for _, tag := range strings.Split(condition, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == current {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package server_test
+
+import (
+	"context"
+	"errors"
+	"github.com/Chronicle20/atlas-rest/requests"
+	"github.com/Chronicle20/atlas-rest/server"
+	"github.com/jtumidanski/api2go/jsonapi"
+	"github.com/sirupsen/logrus/hooks/test"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestIfMatch(t *testing.T) {
+	l, _ := test.NewNullLogger()
+
+	current := character{Id: "1", Name: "Atlas"}
+	version := func(r *http.Request) (string, error) {
+		return server.ComputeETag(current)
+	}
+	h := server.IfMatchMiddleware(l, version, true)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPatch {
+			b, _ := io.ReadAll(r.Body)
+			var input character
+			if err := jsonapi.Unmarshal(b, &input); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			current.Name = input.Name
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		_ = server.SetETag(w, current)
+		_, _ = w.Write([]byte(`{"data":{"type":"characters","id":"1","attributes":{"name":"` + current.Name + `"}}}`))
+	}))
+	s := httptest.NewServer(h)
+	defer s.Close()
+
+	v, err := requests.MakeVersionedGetRequest[character](s.URL)(l, context.Background())
+	if err != nil || v.ETag == "" || v.Value.Name != "Atlas" {
+		t.Fatalf("unexpected versioned resource [%v] [%v]", v, err)
+	}
+
+	_, err = requests.MakePatchRequest[character](s.URL, character{Id: "1", Name: "Unconditional"}, requests.FailOnErrorStatus())(l, context.Background())
+	if !requests.IsClientError(err) || current.Name != "Atlas" {
+		t.Fatal("expected request without If-Match to be rejected")
+	}
+
+	_, err = requests.MakePatchRequest[character](s.URL, character{Id: "1", Name: "Chronicle"}, requests.SetIfMatch(v.ETag))(l, context.Background())
+	if err != nil || current.Name != "Chronicle" {
+		t.Fatalf("expected conditional update to apply [%v]", err)
+	}
+
+	_, err = requests.MakePatchRequest[character](s.URL, character{Id: "1", Name: "Stale"}, requests.SetIfMatch(v.ETag))(l, context.Background())
+	if !errors.Is(err, requests.ErrPreconditionFailed) || current.Name != "Chronicle" {
+		t.Fatalf("expected stale update to fail with precondition failed, got [%v]", err)
+	}
+}
+
+func TestCheckIfMatchUnderLock(t *testing.T) {
+	var mu sync.Mutex
+	current := character{Id: "1", Name: "Atlas"}
+	initial, _ := server.ComputeETag(current)
+
+	applied := 0
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		version, _ := server.ComputeETag(current)
+		if err := server.CheckIfMatch(r, version, true); err != nil {
+			server.WritePreconditionError(w, err, version)
+			return
+		}
+		applied++
+		current.Name = "Updated " + strconv.Itoa(applied)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	var wg sync.WaitGroup
+	codes := make([]int, 10)
+	for i := range codes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodPatch, "/characters/1", nil)
+			req.Header.Set(server.IfMatchHeader, initial)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+			codes[i] = w.Code
+		}()
+	}
+	wg.Wait()
+
+	if applied != 1 {
+		t.Fatalf("expected a single conditional update to apply, applied [%d] with [%v]", applied, codes)
+	}
+
+	req := httptest.NewRequest(http.MethodPatch, "/characters/1", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusPreconditionRequired {
+		t.Fatalf("expected missing condition to be required, got [%d]", w.Code)
+	}
+}
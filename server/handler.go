@@ -2,7 +2,10 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"github.com/Chronicle20/atlas-rest/requestid"
+	"github.com/Chronicle20/atlas-rest/tenantbaggage"
 	"github.com/Chronicle20/atlas-tenant"
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
@@ -33,6 +36,16 @@ func ParseTenant(l logrus.FieldLogger, ctx context.Context, next TenantHandler)
 	return func(w http.ResponseWriter, r *http.Request) {
 		idStr := r.Header.Get(tenant.ID)
 		if idStr == "" {
+			t, err := tenantbaggage.Extract(r.Header)
+			if err == nil {
+				next(tenantLogger(l, t), tenant.WithContext(ctx, t))(w, r)
+				return
+			}
+			if !errors.Is(err, tenantbaggage.ErrNotFound) {
+				l.WithError(err).Errorf("Tenant supplied in baggage is invalid.")
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
 			l.Errorf("%s is not supplied.", tenant.ID)
 			w.WriteHeader(http.StatusBadRequest)
 			return
@@ -80,11 +93,6 @@ func ParseTenant(l logrus.FieldLogger, ctx context.Context, next TenantHandler)
 			return
 		}
 
-		tl := l.
-			WithField("tenant", id.String()).
-			WithField("region", region).
-			WithField("ms.version", fmt.Sprintf("%d.%d", majorVersionVal, minorVersionVal))
-
 		t, err := tenant.Create(id, region, uint16(majorVersionVal), uint16(minorVersionVal))
 		if err != nil {
 			l.Errorf("Failed to create tenant with provided data.")
@@ -93,6 +101,33 @@ func ParseTenant(l logrus.FieldLogger, ctx context.Context, next TenantHandler)
 		}
 
 		tctx := tenant.WithContext(ctx, t)
-		next(tl, tctx)(w, r)
+		next(tenantLogger(l, t), tctx)(w, r)
+	}
+}
+
+func tenantLogger(l logrus.FieldLogger, t tenant.Model) logrus.FieldLogger {
+	return l.
+		WithField("tenant", t.Id().String()).
+		WithField("region", t.Region()).
+		WithField("ms.version", fmt.Sprintf("%d.%d", t.MajorVersion(), t.MinorVersion()))
+}
+
+type RequestIdHandler func(logrus.FieldLogger, context.Context) http.HandlerFunc
+
+// ParseRequestId adopts the request id supplied by the caller, or generates one, and makes it available to the handler
+// through the context and logger. The id is echoed in the response.
+//
+//goland:noinspection GoUnusedExportedFunction
+func ParseRequestId(l logrus.FieldLogger, ctx context.Context, next RequestIdHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestid.Header)
+		if !requestid.Valid(id) {
+			id = requestid.Generate()
+		}
+		w.Header().Set(requestid.Header, id)
+
+		rl := l.WithField("request.id", id)
+		rctx := requestid.WithContext(ctx, id)
+		next(rl, rctx)(w, r.WithContext(requestid.WithContext(r.Context(), id)))
 	}
 }
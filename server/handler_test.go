@@ -3,6 +3,7 @@ package server_test
 import (
 	"context"
 	"errors"
+	"github.com/Chronicle20/atlas-rest/requestid"
 	"github.com/Chronicle20/atlas-rest/requests"
 	"github.com/Chronicle20/atlas-rest/server"
 	"github.com/Chronicle20/atlas-tenant"
@@ -14,6 +15,7 @@ import (
 	"go.opentelemetry.io/otel/trace"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -182,3 +184,106 @@ func TestTenantPropagation(t *testing.T) {
 		t.Fatal(errors.New("invalid tenant").Error())
 	}
 }
+
+func TestRequestIdPropagation(t *testing.T) {
+	l, _ := test.NewNullLogger()
+
+	ictx := requestid.WithContext(context.Background(), "atlas-request")
+
+	req, err := http.NewRequest(http.MethodGet, "www.google.com", nil)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	w := httptest.NewRecorder()
+
+	requests.RequestIdHeaderDecorator(ictx)(req.Header)
+
+	var called = false
+
+	server.ParseRequestId(l, context.Background(), func(l logrus.FieldLogger, rctx context.Context) http.HandlerFunc {
+		called = true
+		id, err := requestid.FromContext(rctx)()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if id != "atlas-request" {
+			t.Fatal(errors.New("invalid request id").Error())
+		}
+		return func(w http.ResponseWriter, r *http.Request) {
+		}
+	})(w, req)
+
+	if !called {
+		t.Fatal(errors.New("invalid request id").Error())
+	}
+	if w.Header().Get(requestid.Header) != "atlas-request" {
+		t.Fatal(errors.New("request id not returned").Error())
+	}
+}
+
+func TestRequestIdGeneration(t *testing.T) {
+	l, _ := test.NewNullLogger()
+
+	req, err := http.NewRequest(http.MethodGet, "www.google.com", nil)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	req.Header.Set(requestid.Header, "invalid request id")
+	w := httptest.NewRecorder()
+
+	var id string
+	server.ParseRequestId(l, context.Background(), func(l logrus.FieldLogger, rctx context.Context) http.HandlerFunc {
+		id, _ = requestid.FromContext(rctx)()
+		return func(w http.ResponseWriter, r *http.Request) {
+		}
+	})(w, req)
+
+	if id == "" || id == "invalid request id" || w.Header().Get(requestid.Header) != id {
+		t.Fatal(errors.New("request id not generated").Error())
+	}
+}
+
+func TestTenantBaggagePropagation(t *testing.T) {
+	l, _ := test.NewNullLogger()
+
+	it, err := tenant.Create(uuid.New(), "GMS", 83, 1)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	ictx := tenant.WithContext(context.Background(), it)
+
+	req, err := http.NewRequest(http.MethodGet, "www.google.com", nil)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	req.Header.Set("baggage", "player.session=abc")
+	w := httptest.NewRecorder()
+
+	requests.TenantHeaderDecorator(ictx, requests.EmitTenantHeaders(false), requests.EmitTenantBaggage(true))(req.Header)
+	if req.Header.Get(tenant.ID) != "" {
+		t.Fatal(errors.New("tenant headers emitted").Error())
+	}
+	if !strings.Contains(req.Header.Get("baggage"), "player.session=abc") {
+		t.Fatal(errors.New("existing baggage discarded").Error())
+	}
+
+	var called = false
+
+	server.ParseTenant(l, context.Background(), func(l logrus.FieldLogger, tctx context.Context) http.HandlerFunc {
+		called = true
+		ot, err := tenant.FromContext(tctx)()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if !it.Is(ot) {
+			t.Fatal(errors.New("invalid tenant").Error())
+		}
+		return func(w http.ResponseWriter, r *http.Request) {
+		}
+	})(w, req)
+
+	if !called {
+		t.Fatal(errors.New("invalid tenant").Error())
+	}
+}
@@ -0,0 +1,235 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/Chronicle20/atlas-rest/tenantbaggage"
+	"github.com/Chronicle20/atlas-tenant"
+	"github.com/sirupsen/logrus"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotencyReplayedHeader = "Idempotent-Replayed"
+)
+
+// StoredResponse is the response recorded for an idempotency key, along with the fingerprint of the request payload
+// which produced it.
+type StoredResponse struct {
+	StatusCode  int
+	Header      http.Header
+	Body        []byte
+	Fingerprint string
+}
+
+// IdempotencyStore holds responses by idempotency key. Begin either returns the stored response for the key, or
+// reserves the key for the caller, reporting false when another call already holds the reservation. Entries are
+// expected to expire, and a store need not report an expired entry.
+type IdempotencyStore interface {
+	Begin(key string) (*StoredResponse, bool)
+	Complete(key string, response StoredResponse)
+	Abandon(key string)
+}
+
+type memoryIdempotencyEntry struct {
+	response *StoredResponse
+	expires  time.Time
+}
+
+// MemoryIdempotencyStore holds entries in memory. Expired entries are ignored on lookup, and removed by a sweep run at
+// most once per ttl.
+type MemoryIdempotencyStore struct {
+	ttl       time.Duration
+	mu        sync.Mutex
+	entries   map[string]memoryIdempotencyEntry
+	nextSweep time.Time
+}
+
+//goland:noinspection GoUnusedExportedFunction
+func NewMemoryIdempotencyStore(ttl time.Duration) *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{ttl: ttl, entries: make(map[string]memoryIdempotencyEntry), nextSweep: time.Now().Add(ttl)}
+}
+
+func (s *MemoryIdempotencyStore) Begin(key string) (*StoredResponse, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.After(s.nextSweep) {
+		for k, e := range s.entries {
+			if now.After(e.expires) {
+				delete(s.entries, k)
+			}
+		}
+		s.nextSweep = now.Add(s.ttl)
+	}
+
+	if e, ok := s.entries[key]; ok && !now.After(e.expires) {
+		return e.response, false
+	}
+	s.entries[key] = memoryIdempotencyEntry{expires: now.Add(s.ttl)}
+	return nil, true
+}
+
+func (s *MemoryIdempotencyStore) Complete(key string, response StoredResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = memoryIdempotencyEntry{response: &response, expires: time.Now().Add(s.ttl)}
+}
+
+func (s *MemoryIdempotencyStore) Abandon(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+}
+
+type recordingResponseWriter struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+}
+
+func (w *recordingResponseWriter) WriteHeader(statusCode int) {
+	if w.statusCode == 0 {
+		w.statusCode = statusCode
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *recordingResponseWriter) Write(b []byte) (int, error) {
+	if w.statusCode == 0 {
+		w.statusCode = http.StatusOK
+	}
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+// idempotencyScope identifies the resource an idempotency key applies to, so that the same key used by another tenant,
+// or against another resource, is treated as distinct.
+func idempotencyScope(r *http.Request) string {
+	tenantId := r.Header.Get(tenant.ID)
+	if tenantId == "" {
+		if t, err := tenantbaggage.Extract(r.Header); err == nil {
+			tenantId = t.Id().String()
+		}
+	}
+	return strings.Join([]string{tenantId, r.Method, r.URL.Path}, " ")
+}
+
+func fingerprint(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+type IdempotencyConfigurator func(c *idempotencyConfig)
+
+type idempotencyConfig struct {
+	maxBodySize int64
+	retryAfter  time.Duration
+}
+
+// SetMaxIdempotentBodySize bounds the body read to fingerprint a request, rejecting larger requests with 413. By
+// default, the body is limited to 10 MiB.
+//
+//goland:noinspection GoUnusedExportedFunction
+func SetMaxIdempotentBodySize(size int64) IdempotencyConfigurator {
+	return func(c *idempotencyConfig) {
+		c.maxBodySize = size
+	}
+}
+
+// SetIdempotencyRetryAfter is the delay advertised to a client whose key is still in progress. By default, one second.
+//
+//goland:noinspection GoUnusedExportedFunction
+func SetIdempotencyRetryAfter(delay time.Duration) IdempotencyConfigurator {
+	return func(c *idempotencyConfig) {
+		c.retryAfter = delay
+	}
+}
+
+// IdempotencyMiddleware replays the stored response for POST and PATCH requests which repeat an idempotency key for
+// the same tenant, method and path instead of executing the handler again. A key still in progress is rejected with
+// 409 and Retry-After, and a repeated key with a different payload with 422; both echo the key so that clients can
+// tell them from other conflicts. Server errors are not stored so that the call may be retried.
+//
+//goland:noinspection GoUnusedExportedFunction
+func IdempotencyMiddleware(l logrus.FieldLogger, store IdempotencyStore, configurators ...IdempotencyConfigurator) func(next http.Handler) http.Handler {
+	c := &idempotencyConfig{maxBodySize: 10 << 20, retryAfter: time.Second}
+	for _, configurator := range configurators {
+		configurator(c)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" || (r.Method != http.MethodPost && r.Method != http.MethodPatch) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, c.maxBodySize))
+			_ = r.Body.Close()
+			if err != nil {
+				var mbe *http.MaxBytesError
+				if errors.As(err, &mbe) {
+					l.Errorf("Request with %s [%s] has a body exceeding [%d] bytes.", IdempotencyKeyHeader, key, mbe.Limit)
+					w.WriteHeader(http.StatusRequestEntityTooLarge)
+					return
+				}
+				l.WithError(err).Errorf("Unable to read request body.")
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			fp := fingerprint(body)
+
+			sk := idempotencyScope(r) + " " + key
+			stored, ok := store.Begin(sk)
+			if !ok {
+				if stored == nil {
+					l.Warnf("Request with %s [%s] is already in progress.", IdempotencyKeyHeader, key)
+					w.Header().Set(IdempotencyKeyHeader, key)
+					w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(c.retryAfter.Seconds()))))
+					w.WriteHeader(http.StatusConflict)
+					return
+				}
+				if stored.Fingerprint != fp {
+					l.Warnf("Request with %s [%s] does not match the payload of the original request.", IdempotencyKeyHeader, key)
+					w.Header().Set(IdempotencyKeyHeader, key)
+					w.WriteHeader(http.StatusUnprocessableEntity)
+					return
+				}
+				l.Debugf("Replaying response for %s [%s].", IdempotencyKeyHeader, key)
+				for k, v := range stored.Header {
+					w.Header()[k] = v
+				}
+				w.Header().Set(IdempotencyReplayedHeader, "true")
+				w.WriteHeader(stored.StatusCode)
+				_, err = w.Write(stored.Body)
+				if err != nil {
+					l.WithError(err).Errorf("Unable to write response.")
+				}
+				return
+			}
+
+			rw := &recordingResponseWriter{ResponseWriter: w}
+			defer func() {
+				if rw.statusCode == 0 || rw.statusCode >= http.StatusInternalServerError {
+					store.Abandon(sk)
+					return
+				}
+				store.Complete(sk, StoredResponse{StatusCode: rw.statusCode, Header: w.Header().Clone(), Body: rw.body.Bytes(), Fingerprint: fp})
+			}()
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
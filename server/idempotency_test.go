@@ -0,0 +1,223 @@
+package server_test
+
+import (
+	"context"
+	"errors"
+	"github.com/Chronicle20/atlas-rest/requests"
+	"github.com/Chronicle20/atlas-rest/server"
+	"github.com/Chronicle20/atlas-rest/tenantbaggage"
+	"github.com/Chronicle20/atlas-tenant"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus/hooks/test"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyReplay(t *testing.T) {
+	l, _ := test.NewNullLogger()
+
+	calls := 0
+	h := server.IdempotencyMiddleware(l, server.NewMemoryIdempotencyStore(time.Minute))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("created"))
+	}))
+
+	tenantId := uuid.New().String()
+	key := uuid.New().String()
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/characters", nil)
+		req.Header.Set(tenant.ID, tenantId)
+		req.Header.Set(server.IdempotencyKeyHeader, key)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		if w.Code != http.StatusCreated || w.Body.String() != "created" {
+			t.Fatalf("unexpected response [%d] [%s]", w.Code, w.Body.String())
+		}
+		if i == 1 && w.Header().Get(server.IdempotencyReplayedHeader) != "true" {
+			t.Fatal("expected replayed response")
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("expected handler to execute once, executed [%d]", calls)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/characters", nil)
+	req.Header.Set(tenant.ID, uuid.New().String())
+	req.Header.Set(server.IdempotencyKeyHeader, key)
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if calls != 2 {
+		t.Fatalf("expected key to be scoped by tenant, executed [%d]", calls)
+	}
+}
+
+func TestIdempotencyServerErrorNotStored(t *testing.T) {
+	l, _ := test.NewNullLogger()
+
+	calls := 0
+	h := server.IdempotencyMiddleware(l, server.NewMemoryIdempotencyStore(time.Minute))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+
+	key := uuid.New().String()
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodPatch, "/characters/1", nil)
+		req.Header.Set(server.IdempotencyKeyHeader, key)
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	if calls != 2 {
+		t.Fatalf("expected handler to execute twice, executed [%d]", calls)
+	}
+}
+
+func TestIdempotencyScopeAndPayload(t *testing.T) {
+	l, _ := test.NewNullLogger()
+
+	calls := 0
+	h := server.IdempotencyMiddleware(l, server.NewMemoryIdempotencyStore(time.Minute))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		b, _ := io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write(b)
+	}))
+
+	key := uuid.New().String()
+	serve := func(method string, path string, body string, decorate func(h http.Header)) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(server.IdempotencyKeyHeader, key)
+		decorate(req.Header)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+	none := func(h http.Header) {}
+
+	w := serve(http.MethodPost, "/characters", "atlas", none)
+	if w.Code != http.StatusCreated || w.Body.String() != "atlas" {
+		t.Fatalf("unexpected response [%d] [%s]", w.Code, w.Body.String())
+	}
+	w = serve(http.MethodPost, "/characters", "chronicle", none)
+	if w.Code != http.StatusUnprocessableEntity || calls != 1 {
+		t.Fatalf("expected payload mismatch to be rejected, got [%d] after [%d] calls", w.Code, calls)
+	}
+
+	serve(http.MethodPost, "/accounts", "atlas", none)
+	serve(http.MethodPatch, "/characters", "atlas", none)
+	if calls != 3 {
+		t.Fatalf("expected key to be scoped by method and path, executed [%d]", calls)
+	}
+
+	for _, id := range []uuid.UUID{uuid.New(), uuid.New()} {
+		it, err := tenant.Create(id, "GMS", 83, 1)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		for i := 0; i < 2; i++ {
+			serve(http.MethodPost, "/characters", "atlas", func(h http.Header) {
+				_ = tenantbaggage.Inject(h, it)
+			})
+		}
+	}
+	if calls != 5 {
+		t.Fatalf("expected key to be scoped by baggage tenant, executed [%d]", calls)
+	}
+}
+
+func TestMemoryIdempotencyStoreExpiry(t *testing.T) {
+	s := server.NewMemoryIdempotencyStore(10 * time.Millisecond)
+
+	if _, ok := s.Begin("key"); !ok {
+		t.Fatal("expected key to be reserved")
+	}
+	s.Complete("key", server.StoredResponse{StatusCode: http.StatusCreated})
+	if r, ok := s.Begin("key"); ok || r == nil || r.StatusCode != http.StatusCreated {
+		t.Fatal("expected stored response")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if _, ok := s.Begin("key"); !ok {
+		t.Fatal("expected expired key to be reserved again")
+	}
+}
+
+func TestIdempotencyConflictOnClient(t *testing.T) {
+	l, _ := test.NewNullLogger()
+
+	var calls atomic.Int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	s := httptest.NewServer(server.IdempotencyMiddleware(l, server.NewMemoryIdempotencyStore(time.Minute))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			close(started)
+			<-release
+		}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"data":{"type":"characters","id":"1","attributes":{"name":"atlas"}}}`))
+	})))
+	defer s.Close()
+
+	key := uuid.New().String()
+	post := func(name string) (character, error) {
+		return requests.MakePostRequest[character](s.URL, character{Name: name}, requests.SetIdempotencyKey(key), requests.SetRetries(3))(l, context.Background())
+	}
+
+	first := make(chan error, 1)
+	go func() {
+		_, err := post("atlas")
+		first <- err
+	}()
+	<-started
+
+	second := make(chan error, 1)
+	var c character
+	go func() {
+		var err error
+		c, err = post("atlas")
+		second <- err
+	}()
+	time.Sleep(200 * time.Millisecond)
+	close(release)
+
+	if err := <-first; err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := <-second; err != nil {
+		t.Fatalf("expected request in progress to be retried, got [%v]", err)
+	}
+	if c.Name != "atlas" || calls.Load() != 1 {
+		t.Fatalf("expected replayed response, got [%s] after [%d] calls", c.Name, calls.Load())
+	}
+
+	began := time.Now()
+	_, err := post("chronicle")
+	if !errors.Is(err, requests.ErrIdempotencyConflict) || requests.IsRetryable(err) {
+		t.Fatalf("expected payload mismatch to fail without retry, got [%v]", err)
+	}
+	if time.Since(began) > 500*time.Millisecond || calls.Load() != 1 {
+		t.Fatal("expected payload mismatch not to be retried")
+	}
+}
+
+func TestIdempotentBodyLimit(t *testing.T) {
+	l, _ := test.NewNullLogger()
+
+	calls := 0
+	h := server.IdempotencyMiddleware(l, server.NewMemoryIdempotencyStore(time.Minute), server.SetMaxIdempotentBodySize(8))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/characters", strings.NewReader(strings.Repeat("a", 64)))
+	req.Header.Set(server.IdempotencyKeyHeader, uuid.New().String())
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge || calls != 0 {
+		t.Fatalf("expected oversized body to be rejected, got [%d]", w.Code)
+	}
+}
@@ -2,8 +2,10 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"github.com/Chronicle20/atlas-rest/redact"
 	"github.com/sirupsen/logrus"
 	"io"
 	"log"
@@ -58,7 +60,7 @@ func CommonHeader(next http.Handler) http.Handler {
 func LoggingMiddleware(l logrus.FieldLogger) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			l.Debugf("Handling [%s] request on [%s]", r.Method, r.RequestURI)
+			l.Debugf("Handling [%s] request on [%s]", r.Method, redact.Default().URL(r.RequestURI))
 			next.ServeHTTP(w, r)
 		})
 	}
@@ -78,6 +80,9 @@ type Builder struct {
 	basePath          string
 	routeInitializers []RouteInitializer
 	routerProducer    RouteProducer
+	tlsCertFile       string
+	tlsKeyFile        string
+	tlsClientCAFile   string
 }
 
 func New(l *logrus.Logger) *Builder {
@@ -161,7 +166,41 @@ func (sb *Builder) SetRouterProducer(producer RouteProducer) *Builder {
 	return sb
 }
 
+// SetTLS serves over TLS using the supplied key pair, which is reloaded when either file changes.
+func (sb *Builder) SetTLS(certFile string, keyFile string) *Builder {
+	sb.tlsCertFile = certFile
+	sb.tlsKeyFile = keyFile
+	return sb
+}
+
+// SetClientCA requires clients to present a certificate signed by a certificate authority in the supplied file.
+func (sb *Builder) SetClientCA(caFile string) *Builder {
+	sb.tlsClientCAFile = caFile
+	return sb
+}
+
+// Run starts the server in the background, stopping it once the context is done. A server configured for TLS which
+// cannot load its certificates logs the error and is not started; use Start to handle the error instead.
 func (sb *Builder) Run() {
+	err := sb.Start()
+	if err != nil {
+		sb.l.WithError(err).Errorf("Unable to start server [%s:%s].", sb.host, sb.port)
+	}
+}
+
+// Start is Run returning the error of a server configured for TLS which cannot load its certificates, rather than
+// leaving it unserved.
+func (sb *Builder) Start() error {
+	var tc *tls.Config
+	if sb.tlsCertFile != "" {
+		var err error
+		tc, err = sb.tlsConfig()
+		if err != nil {
+			_ = sb.w.Close()
+			return fmt.Errorf("unable to configure TLS: %w", err)
+		}
+	}
+
 	go func() {
 		hs := http.Server{
 			Addr:         fmt.Sprintf("%s:%s", sb.host, sb.port),
@@ -170,6 +209,7 @@ func (sb *Builder) Run() {
 			ReadTimeout:  sb.readTimeout,
 			WriteTimeout: sb.writeTimeout,
 			IdleTimeout:  sb.idleTimeout,
+			TLSConfig:    tc,
 		}
 
 		sb.l.Infof("Starting server [%s:%s]", sb.host, sb.port)
@@ -177,10 +217,15 @@ func (sb *Builder) Run() {
 		ctx, cancel := context.WithCancel(sb.ctx)
 		defer cancel()
 
+		sb.wg.Add(1)
 		go func() {
-			sb.wg.Add(1)
 			defer sb.wg.Done()
-			err := hs.ListenAndServe()
+			var err error
+			if hs.TLSConfig != nil {
+				err = hs.ListenAndServeTLS("", "")
+			} else {
+				err = hs.ListenAndServe()
+			}
 			if !errors.Is(err, http.ErrServerClosed) {
 				sb.l.WithError(err).Errorf("Error while serving.")
 				return
@@ -198,4 +243,5 @@ func (sb *Builder) Run() {
 			sb.l.WithError(err).Errorf("Closing log writer.")
 		}
 	}()
+	return nil
 }
@@ -0,0 +1,143 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"github.com/Chronicle20/atlas-rest/signature"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type nonceCache struct {
+	ttl       time.Duration
+	mu        sync.Mutex
+	entries   map[string]time.Time
+	nextSweep time.Time
+}
+
+type SignatureConfigurator func(c *signatureConfig)
+
+type signatureConfig struct {
+	maxBodySize int64
+}
+
+// SetMaxSignedBodySize bounds the body read to verify a signature, rejecting larger requests with 413. By default, the
+// body is limited to 10 MiB.
+//
+//goland:noinspection GoUnusedExportedFunction
+func SetMaxSignedBodySize(size int64) SignatureConfigurator {
+	return func(c *signatureConfig) {
+		c.maxBodySize = size
+	}
+}
+
+// seen records the nonce, reporting whether it was already recorded. Expired nonces are ignored on lookup, and removed
+// by a sweep run at most once per ttl.
+func (nc *nonceCache) seen(nonce string) bool {
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+
+	now := time.Now()
+	if now.After(nc.nextSweep) {
+		for k, expires := range nc.entries {
+			if now.After(expires) {
+				delete(nc.entries, k)
+			}
+		}
+		nc.nextSweep = now.Add(nc.ttl)
+	}
+	if expires, ok := nc.entries[nonce]; ok && !now.After(expires) {
+		return true
+	}
+	nc.entries[nonce] = now.Add(nc.ttl)
+	return false
+}
+
+// SignatureMiddleware verifies requests signed with a key from the keyring. Requests which are unsigned, signed with
+// an unknown key, carry a timestamp outside maxSkew or repeat a nonce are rejected. As the signature covers the body as
+// sent, this middleware must run before any which decode the request body.
+//
+//goland:noinspection GoUnusedExportedFunction
+func SignatureMiddleware(l logrus.FieldLogger, keyring *signature.Keyring, maxSkew time.Duration, configurators ...SignatureConfigurator) func(next http.Handler) http.Handler {
+	c := &signatureConfig{maxBodySize: 10 << 20}
+	for _, configurator := range configurators {
+		configurator(c)
+	}
+	nonces := &nonceCache{ttl: 2 * maxSkew, entries: make(map[string]time.Time), nextSweep: time.Now().Add(2 * maxSkew)}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			keyId, sig, err := signature.Parse(r.Header.Get(signature.Header))
+			if err != nil {
+				l.Errorf("%s is not supplied.", signature.Header)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			k, ok := keyring.Get(keyId)
+			if !ok {
+				l.Errorf("Signing key [%s] is not recognized.", keyId)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			ts := r.Header.Get(signature.TimestampHeader)
+			tsVal, err := strconv.ParseInt(ts, 10, 64)
+			if err != nil {
+				l.Errorf("%s is not supplied.", signature.TimestampHeader)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			skew := time.Since(time.Unix(tsVal, 0))
+			if skew > maxSkew || skew < -maxSkew {
+				l.Errorf("Signed request is stale by [%s].", skew)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			nonce := r.Header.Get(signature.NonceHeader)
+			if nonce == "" {
+				l.Errorf("%s is not supplied.", signature.NonceHeader)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			var body []byte
+			if r.Body != nil {
+				body, err = io.ReadAll(http.MaxBytesReader(w, r.Body, c.maxBodySize))
+				if err != nil {
+					var mbe *http.MaxBytesError
+					if errors.As(err, &mbe) {
+						l.Errorf("Signed request body exceeds [%d] bytes.", mbe.Limit)
+						w.WriteHeader(http.StatusRequestEntityTooLarge)
+						return
+					}
+					l.WithError(err).Errorf("Unable to read request body.")
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				_ = r.Body.Close()
+				r.Body = io.NopCloser(bytes.NewReader(body))
+			}
+
+			canonical := signature.Canonical(r.Method, r.URL.RequestURI(), r.Header, ts, nonce, body)
+			if !signature.Verify(k, canonical, sig) {
+				l.Errorf("Signature from key [%s] is invalid.", keyId)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			if nonces.seen(keyId + ":" + nonce) {
+				l.Errorf("Signed request with nonce [%s] has been replayed.", nonce)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
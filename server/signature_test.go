@@ -0,0 +1,165 @@
+package server_test
+
+import (
+	"context"
+	"errors"
+	"github.com/Chronicle20/atlas-rest/requests"
+	"github.com/Chronicle20/atlas-rest/server"
+	"github.com/Chronicle20/atlas-rest/signature"
+	"github.com/Chronicle20/atlas-rest/tenantbaggage"
+	"github.com/Chronicle20/atlas-tenant"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus/hooks/test"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignedRequest(t *testing.T) {
+	l, _ := test.NewNullLogger()
+
+	previous := signature.Key{Id: "2024", Secret: []byte("previous")}
+	current := signature.Key{Id: "2025", Secret: []byte("current")}
+	keyring := signature.NewKeyring(previous, current)
+
+	var replay *http.Request
+	s := httptest.NewServer(server.SignatureMiddleware(l, keyring, time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		replay = r.Clone(context.Background())
+		w.WriteHeader(http.StatusNotFound)
+	})))
+	defer s.Close()
+
+	for _, k := range []signature.Key{previous, current} {
+		_, err := requests.MakeGetRequest[character](s.URL+"/characters?name=atlas", requests.SetSigningKey(k))(l, context.Background())
+		if !errors.Is(err, requests.ErrNotFound) {
+			t.Fatalf("expected signed request with key [%s] to be accepted, got [%v]", k.Id, err)
+		}
+	}
+
+	_, err := requests.MakeGetRequest[character](s.URL, requests.SetSigningKey(signature.Key{Id: "2025", Secret: []byte("forged")}))(l, context.Background())
+	if err == nil || errors.Is(err, requests.ErrNotFound) {
+		t.Fatalf("expected forged signature to be rejected, got [%v]", err)
+	}
+
+	keyring.Remove(previous.Id)
+	_, err = requests.MakeGetRequest[character](s.URL, requests.SetSigningKey(previous))(l, context.Background())
+	if err == nil || errors.Is(err, requests.ErrNotFound) {
+		t.Fatalf("expected retired key to be rejected, got [%v]", err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, s.URL+replay.URL.RequestURI(), nil)
+	req.Header = replay.Header
+	r, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	_ = r.Body.Close()
+	if r.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected replayed request to be rejected, got [%d]", r.StatusCode)
+	}
+}
+
+func TestSignedUpload(t *testing.T) {
+	l, _ := test.NewNullLogger()
+
+	k := signature.Key{Id: "2025", Secret: []byte("current")}
+	var received string
+	s := httptest.NewServer(server.SignatureMiddleware(l, signature.NewKeyring(k), time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		received = string(b)
+		w.WriteHeader(http.StatusNoContent)
+	})))
+	defer s.Close()
+
+	_, err := requests.MakeBinaryRequest[character](http.MethodPut, s.URL, "application/octet-stream", requests.ReaderSource(strings.NewReader("wz-content")), requests.SetSigningKey(k))(l, context.Background())
+	if err != nil || received != "wz-content" {
+		t.Fatalf("expected signed single-use upload to be accepted, got [%s] [%v]", received, err)
+	}
+
+	var reported []int64
+	body := strings.Repeat("export", 1000)
+	source := requests.NewBodySource(func() (io.ReadCloser, int64, error) {
+		return io.NopCloser(strings.NewReader(body)), int64(len(body)), nil
+	})
+	_, err = requests.MakeBinaryRequest[character](http.MethodPut, s.URL, "application/octet-stream", source, requests.SetSigningKey(k), requests.SetProgress(func(transferred int64, _ int64) {
+		reported = append(reported, transferred)
+	}))(l, context.Background())
+	if err != nil || received != body {
+		t.Fatalf("expected signed upload to be accepted, got [%v]", err)
+	}
+	for i := 1; i < len(reported); i++ {
+		if reported[i] < reported[i-1] {
+			t.Fatalf("expected progress to be reported once for the upload, got [%v]", reported)
+		}
+	}
+}
+
+func TestSignatureCoversTenantBaggage(t *testing.T) {
+	l, _ := test.NewNullLogger()
+
+	k := signature.Key{Id: "2025", Secret: []byte("current")}
+	s := httptest.NewServer(server.SignatureMiddleware(l, signature.NewKeyring(k), time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})))
+	defer s.Close()
+
+	it, err := tenant.Create(uuid.New(), "GMS", 83, 1)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	other, err := tenant.Create(uuid.New(), "GMS", 83, 1)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	send := func(tamper bool) int {
+		req, _ := http.NewRequest(http.MethodGet, s.URL+"/characters", nil)
+		_ = tenantbaggage.Inject(req.Header, it)
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		nonce := uuid.New().String()
+		canonical := signature.Canonical(req.Method, req.URL.RequestURI(), req.Header, ts, nonce, nil)
+		req.Header.Set(signature.TimestampHeader, ts)
+		req.Header.Set(signature.NonceHeader, nonce)
+		req.Header.Set(signature.Header, signature.Format(k.Id, signature.Sign(k, canonical)))
+		if tamper {
+			_ = tenantbaggage.Inject(req.Header, other)
+		}
+		r, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		_ = r.Body.Close()
+		return r.StatusCode
+	}
+
+	if code := send(false); code != http.StatusNoContent {
+		t.Fatalf("expected signed request to be accepted, got [%d]", code)
+	}
+	if code := send(true); code != http.StatusUnauthorized {
+		t.Fatalf("expected request with altered tenant baggage to be rejected, got [%d]", code)
+	}
+}
+
+func TestSignedBodyLimit(t *testing.T) {
+	l, _ := test.NewNullLogger()
+
+	k := signature.Key{Id: "2025", Secret: []byte("current")}
+	s := httptest.NewServer(server.SignatureMiddleware(l, signature.NewKeyring(k), time.Minute, server.SetMaxSignedBodySize(16))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})))
+	defer s.Close()
+
+	_, err := requests.MakeBinaryRequest[character](http.MethodPut, s.URL, "application/octet-stream", requests.BytesSource([]byte("wz")), requests.SetSigningKey(k))(l, context.Background())
+	if err != nil {
+		t.Fatalf("expected body within limit to be accepted, got [%v]", err)
+	}
+	_, err = requests.MakeBinaryRequest[character](http.MethodPut, s.URL, "application/octet-stream", requests.BytesSource([]byte(strings.Repeat("wz", 16))), requests.SetSigningKey(k), requests.FailOnErrorStatus())(l, context.Background())
+	var se requests.StatusError
+	if !errors.As(err, &se) || se.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected body beyond limit to be rejected, got [%v]", err)
+	}
+}
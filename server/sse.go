@@ -0,0 +1,173 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const LastEventIdHeader = "Last-Event-ID"
+
+var ErrStreamClosed = errors.New("event stream closed")
+
+type Event struct {
+	Id    string
+	Event string
+	Data  string
+	Retry time.Duration
+}
+
+// EventStream writes Server-Sent Events to a client, flushing each as it is sent. It is safe for concurrent use.
+type EventStream struct {
+	mu          sync.Mutex
+	w           http.ResponseWriter
+	rc          *http.ResponseController
+	lastEventId string
+	closed      bool
+}
+
+// LastEventId is the id of the last event received by a reconnecting client, from which the stream should resume.
+func (s *EventStream) LastEventId() string {
+	return s.lastEventId
+}
+
+func (s *EventStream) Send(e Event) error {
+	sb := strings.Builder{}
+	if e.Id != "" {
+		sb.WriteString("id: " + sanitizeField(e.Id) + "\n")
+	}
+	if e.Event != "" {
+		sb.WriteString("event: " + sanitizeField(e.Event) + "\n")
+	}
+	if e.Retry > 0 {
+		sb.WriteString(fmt.Sprintf("retry: %d\n", e.Retry.Milliseconds()))
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(e.Data, "\r\n", "\n"), "\n") {
+		sb.WriteString("data: " + line + "\n")
+	}
+	sb.WriteString("\n")
+	return s.write(sb.String())
+}
+
+func (s *EventStream) heartbeat() error {
+	return s.write(": heartbeat\n\n")
+}
+
+func (s *EventStream) write(frame string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrStreamClosed
+	}
+	_, err := s.w.Write([]byte(frame))
+	if err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+func (s *EventStream) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+}
+
+// canFlush reports whether the writer, or any writer it wraps, supports flushing.
+func canFlush(w http.ResponseWriter) bool {
+	for {
+		switch t := w.(type) {
+		case http.Flusher:
+			return true
+		case interface{ FlushError() error }:
+			return true
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = t.Unwrap()
+		default:
+			return false
+		}
+	}
+}
+
+func sanitizeField(v string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(v)
+}
+
+type EventStreamHandler func(l logrus.FieldLogger, ctx context.Context, s *EventStream) error
+
+// ServeEvents upgrades the request to a Server-Sent Events stream, sending a heartbeat comment at the supplied interval
+// to keep intermediaries from closing an idle connection. The context passed to next, which generally carries the
+// tenant, is cancelled once the client disconnects or a write fails.
+//
+//goland:noinspection GoUnusedExportedFunction
+func ServeEvents(l logrus.FieldLogger, ctx context.Context, heartbeat time.Duration, next EventStreamHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !canFlush(w) {
+			l.Errorf("Unable to stream events, response cannot be flushed.")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		s := &EventStream{w: w, rc: http.NewResponseController(w), lastEventId: r.Header.Get(LastEventIdHeader)}
+
+		// the server write timeout would otherwise terminate the stream.
+		err := s.rc.SetWriteDeadline(time.Time{})
+		if err != nil && !errors.Is(err, http.ErrNotSupported) {
+			l.WithError(err).Errorf("Unable to clear write deadline for event stream.")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		h := w.Header()
+		h.Set("Content-Type", "text/event-stream")
+		h.Set("Cache-Control", "no-cache")
+		h.Set("Connection", "keep-alive")
+		h.Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+
+		err = s.rc.Flush()
+		if err != nil {
+			l.WithError(err).Errorf("Unable to stream events, response cannot be flushed.")
+			return
+		}
+
+		sctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		stop := context.AfterFunc(r.Context(), cancel)
+		defer stop()
+
+		var wg sync.WaitGroup
+		if heartbeat > 0 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				t := time.NewTicker(heartbeat)
+				defer t.Stop()
+				for {
+					select {
+					case <-sctx.Done():
+						return
+					case <-t.C:
+						if err := s.heartbeat(); err != nil {
+							l.WithError(err).Debugf("Client disconnected from event stream.")
+							cancel()
+							return
+						}
+					}
+				}
+			}()
+		}
+
+		err = next(l, sctx, s)
+		cancel()
+		wg.Wait()
+		s.close()
+		if err != nil && !errors.Is(err, context.Canceled) {
+			l.WithError(err).Errorf("Event stream terminated with error.")
+		}
+	}
+}
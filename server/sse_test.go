@@ -0,0 +1,181 @@
+package server_test
+
+import (
+	"context"
+	"github.com/Chronicle20/atlas-rest/requests"
+	"github.com/Chronicle20/atlas-rest/server"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestEventStreamReconnect(t *testing.T) {
+	l, _ := test.NewNullLogger()
+
+	connections := 0
+	var resumedFrom []string
+	s := httptest.NewServer(server.CompressionMiddleware(l, 1024)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		connections++
+		if connections > 2 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		server.ServeEvents(l, context.Background(), 5*time.Millisecond, func(l logrus.FieldLogger, ctx context.Context, es *server.EventStream) error {
+			resumedFrom = append(resumedFrom, es.LastEventId())
+			if es.LastEventId() == "" {
+				_ = es.Send(server.Event{Id: "1", Event: "progress", Data: "10", Retry: 10 * time.Millisecond})
+				time.Sleep(20 * time.Millisecond)
+				return es.Send(server.Event{Id: "2", Event: "progress", Data: "20"})
+			}
+			return es.Send(server.Event{Id: "3", Data: "done\nok"})
+		})(w, r)
+	})))
+	defer s.Close()
+
+	var events []requests.Event
+	for e, err := range requests.Events(l, context.Background(), s.URL) {
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		events = append(events, e)
+	}
+
+	if len(events) != 3 || events[1].Id != "2" || events[1].Event != "progress" || events[2].Event != "message" || events[2].Data != "done\nok" {
+		t.Fatalf("unexpected events [%v]", events)
+	}
+	if len(resumedFrom) != 2 || resumedFrom[1] != "2" {
+		t.Fatalf("expected stream to resume from last event, resumed from [%v]", resumedFrom)
+	}
+}
+
+func TestEventStreamDisconnect(t *testing.T) {
+	l, _ := test.NewNullLogger()
+
+	closed := make(chan struct{})
+	s := httptest.NewServer(server.ServeEvents(l, context.Background(), time.Millisecond, func(l logrus.FieldLogger, ctx context.Context, es *server.EventStream) error {
+		_ = es.Send(server.Event{Data: "hello"})
+		<-ctx.Done()
+		close(closed)
+		return ctx.Err()
+	}))
+	defer s.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events, errs := requests.EventChannel(l, ctx, s.URL)
+	if e := <-events; e.Data != "hello" {
+		t.Fatalf("unexpected event [%v]", e)
+	}
+	cancel()
+
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("expected handler context to be cancelled on client disconnect")
+	}
+	if err := <-errs; err == nil {
+		t.Fatal("expected cancellation error")
+	}
+}
+
+func TestEventStreamOutlivesWriteTimeout(t *testing.T) {
+	l, _ := test.NewNullLogger()
+
+	connections := 0
+	s := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		connections++
+		if connections > 1 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		server.ServeEvents(l, context.Background(), 0, func(l logrus.FieldLogger, ctx context.Context, es *server.EventStream) error {
+			for i := 0; i < 3; i++ {
+				time.Sleep(40 * time.Millisecond)
+				if err := es.Send(server.Event{Data: "tick"}); err != nil {
+					return err
+				}
+			}
+			return nil
+		})(w, r)
+	}))
+	s.Config.WriteTimeout = 50 * time.Millisecond
+	s.Start()
+	defer s.Close()
+
+	count := 0
+	for _, err := range requests.Events(l, context.Background(), s.URL, requests.SetReconnectDelay(time.Millisecond)) {
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		count++
+	}
+	if count != 3 || connections != 2 {
+		t.Fatalf("expected a single uninterrupted stream, received [%d] events over [%d] connections", count, connections)
+	}
+}
+
+type unflushableWriter struct {
+	http.ResponseWriter
+}
+
+func TestEventStreamRequiresFlusher(t *testing.T) {
+	l, _ := test.NewNullLogger()
+
+	called := false
+	h := server.ServeEvents(l, context.Background(), 0, func(l logrus.FieldLogger, ctx context.Context, es *server.EventStream) error {
+		called = true
+		return nil
+	})
+	w := httptest.NewRecorder()
+	h(unflushableWriter{w}, httptest.NewRequest(http.MethodGet, "/events", nil))
+	if called || w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected unflushable writer to be rejected, got [%d]", w.Code)
+	}
+}
+
+func TestEventStreamIdleTimeout(t *testing.T) {
+	l, _ := test.NewNullLogger()
+
+	var connections atomic.Int32
+	var resumedFrom atomic.Value
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch connections.Add(1) {
+		case 1:
+			server.ServeEvents(l, context.Background(), 0, func(l logrus.FieldLogger, ctx context.Context, es *server.EventStream) error {
+				_ = es.Send(server.Event{Id: "1", Data: "stalled"})
+				<-ctx.Done()
+				return ctx.Err()
+			})(w, r)
+		case 2:
+			resumedFrom.Store(r.Header.Get(requests.LastEventIdHeader))
+			server.ServeEvents(l, context.Background(), 10*time.Millisecond, func(l logrus.FieldLogger, ctx context.Context, es *server.EventStream) error {
+				time.Sleep(150 * time.Millisecond)
+				return es.Send(server.Event{Id: "2", Data: "alive"})
+			})(w, r)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer s.Close()
+
+	var events []requests.Event
+	for e, err := range requests.Events(l, context.Background(), s.URL, requests.SetEventStreamIdleTimeout(50*time.Millisecond), requests.SetReconnectDelay(time.Millisecond)) {
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		events = append(events, e)
+	}
+
+	if len(events) != 2 || events[0].Data != "stalled" || events[1].Data != "alive" {
+		t.Fatalf("unexpected events [%v]", events)
+	}
+	if resumedFrom.Load() != "1" {
+		t.Fatalf("expected idle stream to resume from last event, resumed from [%v]", resumedFrom.Load())
+	}
+	if connections.Load() != 3 {
+		t.Fatalf("expected heartbeats to keep the stream open, connected [%d] times", connections.Load())
+	}
+}
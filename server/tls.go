@@ -0,0 +1,195 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+type PeerKey string
+
+const Peer = PeerKey("PEER")
+
+var ErrNoPeer = errors.New("no verified peer")
+
+type PeerIdentity struct {
+	commonName  string
+	dnsNames    []string
+	uris        []string
+	certificate *x509.Certificate
+}
+
+func (p PeerIdentity) CommonName() string {
+	return p.commonName
+}
+
+func (p PeerIdentity) DNSNames() []string {
+	return p.dnsNames
+}
+
+func (p PeerIdentity) URIs() []string {
+	return p.uris
+}
+
+func (p PeerIdentity) Certificate() *x509.Certificate {
+	return p.certificate
+}
+
+func NewPeerIdentity(cert *x509.Certificate) PeerIdentity {
+	uris := make([]string, 0, len(cert.URIs))
+	for _, u := range cert.URIs {
+		uris = append(uris, u.String())
+	}
+	return PeerIdentity{
+		commonName:  cert.Subject.CommonName,
+		dnsNames:    cert.DNSNames,
+		uris:        uris,
+		certificate: cert,
+	}
+}
+
+func WithPeer(ctx context.Context, p PeerIdentity) context.Context {
+	return context.WithValue(ctx, Peer, p)
+}
+
+//goland:noinspection GoUnusedExportedFunction
+func PeerFromContext(ctx context.Context) (PeerIdentity, error) {
+	p, ok := ctx.Value(Peer).(PeerIdentity)
+	if !ok {
+		return PeerIdentity{}, ErrNoPeer
+	}
+	return p, nil
+}
+
+type PeerHandler func(logrus.FieldLogger, context.Context) http.HandlerFunc
+
+// ParsePeer places the identity from the verified client certificate of the request in the context. Requests without a
+// verified client certificate are rejected.
+//
+//goland:noinspection GoUnusedExportedFunction
+func ParsePeer(l logrus.FieldLogger, ctx context.Context, next PeerHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+			l.Errorf("Verified client certificate is not supplied.")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		p := NewPeerIdentity(r.TLS.VerifiedChains[0][0])
+		pl := l.WithField("peer", p.CommonName())
+		next(pl, WithPeer(ctx, p))(w, r)
+	}
+}
+
+// certificateReloader serves a key pair loaded from disk, reloading it when either file is modified. Files are checked
+// at most once per interval.
+type certificateReloader struct {
+	l        logrus.FieldLogger
+	certFile string
+	keyFile  string
+	interval time.Duration
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	modTime   time.Time
+	lastCheck time.Time
+}
+
+func newCertificateReloader(l logrus.FieldLogger, certFile string, keyFile string) (*certificateReloader, error) {
+	cr := &certificateReloader{l: l, certFile: certFile, keyFile: keyFile, interval: time.Second}
+	err := cr.load()
+	if err != nil {
+		return nil, err
+	}
+	return cr, nil
+}
+
+func (cr *certificateReloader) latestModTime() (time.Time, error) {
+	cfi, err := os.Stat(cr.certFile)
+	if err != nil {
+		return time.Time{}, err
+	}
+	kfi, err := os.Stat(cr.keyFile)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if kfi.ModTime().After(cfi.ModTime()) {
+		return kfi.ModTime(), nil
+	}
+	return cfi.ModTime(), nil
+}
+
+func (cr *certificateReloader) load() error {
+	modTime, err := cr.latestModTime()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(cr.certFile, cr.keyFile)
+	if err != nil {
+		return err
+	}
+	cr.cert = &cert
+	cr.modTime = modTime
+	return nil
+}
+
+func (cr *certificateReloader) GetCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+
+	if time.Since(cr.lastCheck) < cr.interval {
+		return cr.cert, nil
+	}
+	cr.lastCheck = time.Now()
+
+	modTime, err := cr.latestModTime()
+	if err != nil || !modTime.After(cr.modTime) {
+		return cr.cert, nil
+	}
+	err = cr.load()
+	if err != nil {
+		cr.l.WithError(err).Errorf("Unable to reload certificate [%s], continuing with previous certificate.", cr.certFile)
+		return cr.cert, nil
+	}
+	cr.l.Infof("Reloaded certificate [%s].", cr.certFile)
+	return cr.cert, nil
+}
+
+func loadCertPool(file string) (*x509.CertPool, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, fmt.Errorf("no certificates found in [%s]", file)
+	}
+	return pool, nil
+}
+
+func (sb *Builder) tlsConfig() (*tls.Config, error) {
+	cr, err := newCertificateReloader(sb.l, sb.tlsCertFile, sb.tlsKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	c := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: cr.GetCertificate,
+	}
+	if sb.tlsClientCAFile != "" {
+		pool, err := loadCertPool(sb.tlsClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		c.ClientCAs = pool
+		c.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return c, nil
+}
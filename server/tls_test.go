@@ -0,0 +1,302 @@
+package server_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"github.com/Chronicle20/atlas-rest/requests"
+	"github.com/Chronicle20/atlas-rest/server"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type authority struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newAuthority(t *testing.T, name string) authority {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	return authority{cert: cert, key: key}
+}
+
+// issue writes a key pair signed by the authority to dir, returning the certificate and key file paths.
+func (a authority) issue(t *testing.T, dir string, name string, serial int64, usage x509.ExtKeyUsage) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, a.cert, &key.PublicKey, a.key)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	kb, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	certFile := filepath.Join(dir, name+".crt")
+	keyFile := filepath.Join(dir, name+".key")
+	writePem(t, certFile, "CERTIFICATE", der)
+	writePem(t, keyFile, "EC PRIVATE KEY", kb)
+	return certFile, keyFile
+}
+
+func (a authority) write(t *testing.T, dir string) string {
+	file := filepath.Join(dir, a.cert.Subject.CommonName+".crt")
+	writePem(t, file, "CERTIFICATE", a.cert.Raw)
+	return file
+}
+
+func writePem(t *testing.T, file string, blockType string, b []byte) {
+	err := os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: b}), 0600)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+}
+
+func freePort(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer ln.Close()
+	return strconv.Itoa(ln.Addr().(*net.TCPAddr).Port)
+}
+
+func peerRoutes(ctx context.Context) server.RouteProducer {
+	return func(l logrus.FieldLogger) http.Handler {
+		return server.ParsePeer(l, ctx, func(l logrus.FieldLogger, ctx context.Context) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				p, err := server.PeerFromContext(ctx)
+				if err != nil {
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				_, _ = w.Write([]byte(`{"data":{"type":"characters","id":"1","attributes":{"name":"` + p.CommonName() + `"}}}`))
+			}
+		})
+	}
+}
+
+// await issues requests until the server accepts connections.
+func await(t *testing.T, get func() error) {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		err := get()
+		if err == nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("server did not become available [%v]", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestMutualTLS(t *testing.T) {
+	l, _ := test.NewNullLogger()
+	dir := t.TempDir()
+
+	ca := newAuthority(t, "atlas-ca")
+	caFile := ca.write(t, dir)
+	serverCert, serverKey := ca.issue(t, dir, "atlas-server", 2, x509.ExtKeyUsageServerAuth)
+	clientCert, clientKey := ca.issue(t, dir, "atlas-client", 3, x509.ExtKeyUsageClientAuth)
+	rogue := newAuthority(t, "rogue-ca")
+	rogueCert, rogueKey := rogue.issue(t, dir, "rogue-client", 4, x509.ExtKeyUsageClientAuth)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	port := freePort(t)
+	server.New(l).WithContext(ctx).SetAddr("127.0.0.1:"+port).SetRouterProducer(peerRoutes(ctx)).SetTLS(serverCert, serverKey).SetClientCA(caFile).Run()
+	url := "https://127.0.0.1:" + port + "/"
+
+	tc, err := requests.NewClientTLSConfig(caFile, clientCert, clientKey)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	var c character
+	await(t, func() error {
+		c, err = requests.MakeGetRequest[character](url, requests.SetTLSConfig(tc))(l, context.Background())
+		return err
+	})
+	if c.Name != "atlas-client" {
+		t.Fatalf("expected peer identity from client certificate, got [%s]", c.Name)
+	}
+
+	anonymous, err := requests.NewClientTLSConfig(caFile, "", "")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	_, err = requests.MakeGetRequest[character](url, requests.SetTLSConfig(anonymous))(l, context.Background())
+	if err == nil {
+		t.Fatal("expected request without client certificate to be rejected")
+	}
+
+	untrusted, err := requests.NewClientTLSConfig(caFile, rogueCert, rogueKey)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	_, err = requests.MakeGetRequest[character](url, requests.SetTLSConfig(untrusted))(l, context.Background())
+	if err == nil {
+		t.Fatal("expected request with untrusted client certificate to be rejected")
+	}
+
+	unverified, err := requests.NewClientTLSConfig("", clientCert, clientKey)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	_, err = requests.MakeGetRequest[character](url, requests.SetTLSConfig(unverified))(l, context.Background())
+	if err == nil || requests.IsRetryable(err) {
+		t.Fatalf("expected server certificate from unknown authority to fail without retry, got [%v]", err)
+	}
+}
+
+func TestCertificateReload(t *testing.T) {
+	l, _ := test.NewNullLogger()
+	dir := t.TempDir()
+
+	ca := newAuthority(t, "atlas-ca")
+	caFile := ca.write(t, dir)
+	serverCert, serverKey := ca.issue(t, dir, "atlas-server", 2, x509.ExtKeyUsageServerAuth)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	port := freePort(t)
+	server.New(l).WithContext(ctx).SetAddr("127.0.0.1:"+port).SetRouterProducer(func(l logrus.FieldLogger) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}).SetTLS(serverCert, serverKey).Run()
+
+	tc, err := requests.NewClientTLSConfig(caFile, "", "")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	hc := &http.Client{Transport: &http.Transport{TLSClientConfig: tc, DisableKeepAlives: true}}
+	serial := func() (int64, error) {
+		r, err := hc.Get("https://127.0.0.1:" + port + "/")
+		if err != nil {
+			return 0, err
+		}
+		_ = r.Body.Close()
+		return r.TLS.PeerCertificates[0].SerialNumber.Int64(), nil
+	}
+
+	var s int64
+	await(t, func() error {
+		s, err = serial()
+		return err
+	})
+	if s != 2 {
+		t.Fatalf("expected initial certificate, got serial [%d]", s)
+	}
+
+	ca.issue(t, dir, "atlas-server", 5, x509.ExtKeyUsageServerAuth)
+	future := time.Now().Add(time.Minute)
+	_ = os.Chtimes(serverCert, future, future)
+	_ = os.Chtimes(serverKey, future, future)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for s != 5 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected reloaded certificate, got serial [%d]", s)
+		}
+		time.Sleep(100 * time.Millisecond)
+		s, err = serial()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+}
+
+func TestParsePeerRequiresVerifiedCertificate(t *testing.T) {
+	l, _ := test.NewNullLogger()
+
+	s := httptest.NewServer(peerRoutes(context.Background())(l))
+	defer s.Close()
+
+	r, err := http.Get(s.URL)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	_ = r.Body.Close()
+	if r.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected unauthorized without client certificate, got [%d]", r.StatusCode)
+	}
+}
+
+func TestInvalidTLSConfiguration(t *testing.T) {
+	l, hook := test.NewNullLogger()
+	dir := t.TempDir()
+
+	err := server.New(l).SetAddr("127.0.0.1:"+freePort(t)).SetTLS(filepath.Join(dir, "missing.crt"), filepath.Join(dir, "missing.key")).Start()
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected missing certificate to fail the start, got [%v]", err)
+	}
+
+	server.New(l).SetAddr("127.0.0.1:"+freePort(t)).SetTLS(filepath.Join(dir, "missing.crt"), filepath.Join(dir, "missing.key")).Run()
+	if hook.LastEntry() == nil || hook.LastEntry().Level != logrus.ErrorLevel {
+		t.Fatal("expected missing certificate to be logged as an error")
+	}
+}
+
+func TestClientTLSConfigErrors(t *testing.T) {
+	dir := t.TempDir()
+	empty := filepath.Join(dir, "empty.crt")
+	_ = os.WriteFile(empty, []byte("not a certificate"), 0600)
+
+	if _, err := requests.NewClientTLSConfig(empty, "", ""); err == nil {
+		t.Fatal("expected certificate authority file without certificates to be rejected")
+	}
+	if _, err := requests.NewClientTLSConfig("", filepath.Join(dir, "missing.crt"), filepath.Join(dir, "missing.key")); err == nil {
+		t.Fatal("expected missing client key pair to be rejected")
+	}
+	c, err := requests.NewClientTLSConfig("", "", "")
+	if err != nil || c.MinVersion != tls.VersionTLS12 || c.RootCAs != nil || len(c.Certificates) != 0 {
+		t.Fatalf("unexpected default client configuration [%v]", err)
+	}
+}
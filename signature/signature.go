@@ -0,0 +1,140 @@
+package signature
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/Chronicle20/atlas-rest/tenantbaggage"
+	"github.com/Chronicle20/atlas-tenant"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/propagation"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const (
+	Header          = "X-Atlas-Signature"
+	TimestampHeader = "X-Atlas-Timestamp"
+	NonceHeader     = "X-Atlas-Nonce"
+)
+
+var ErrMalformedSignature = errors.New("malformed signature")
+
+type Key struct {
+	Id     string
+	Secret []byte
+}
+
+// Keyring holds the keys which are currently accepted, allowing a new key to be introduced before the previous one is
+// retired.
+type Keyring struct {
+	mu   sync.RWMutex
+	keys map[string]Key
+}
+
+//goland:noinspection GoUnusedExportedFunction
+func NewKeyring(keys ...Key) *Keyring {
+	kr := &Keyring{keys: make(map[string]Key)}
+	for _, k := range keys {
+		kr.keys[k.Id] = k
+	}
+	return kr
+}
+
+func (kr *Keyring) Add(k Key) {
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+	kr.keys[k.Id] = k
+}
+
+func (kr *Keyring) Remove(id string) {
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+	delete(kr.keys, id)
+}
+
+func (kr *Keyring) Get(id string) (Key, bool) {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+	k, ok := kr.keys[id]
+	return k, ok
+}
+
+// Canonical produces the string which is signed for a request. It covers the method, the request uri, the tenant
+// headers, the tenant members of the baggage header, the timestamp, the nonce and a hash of the body.
+func Canonical(method string, requestUri string, h http.Header, timestamp string, nonce string, body []byte) []byte {
+	bh := sha256.Sum256(body)
+	return CanonicalWithBodyHash(method, requestUri, h, timestamp, nonce, bh[:])
+}
+
+// CanonicalWithBodyHash behaves as Canonical, taking the sha256 hash of the body so that it may be computed while
+// streaming.
+func CanonicalWithBodyHash(method string, requestUri string, h http.Header, timestamp string, nonce string, bodyHash []byte) []byte {
+	var b bytes.Buffer
+	b.WriteString(strings.ToUpper(method))
+	b.WriteByte('\n')
+	b.WriteString(requestUri)
+	b.WriteByte('\n')
+	for _, k := range []string{tenant.ID, tenant.Region, tenant.MajorVersion, tenant.MinorVersion} {
+		b.WriteString(h.Get(k))
+		b.WriteByte('\n')
+	}
+	// members rather than the raw header, as intermediaries may add members of their own or reorder them.
+	bg := baggage.FromContext(propagation.Baggage{}.Extract(context.Background(), propagation.HeaderCarrier(h)))
+	for _, k := range []string{tenantbaggage.IdKey, tenantbaggage.RegionKey, tenantbaggage.MajorVersionKey, tenantbaggage.MinorVersionKey} {
+		b.WriteString(bg.Member(k).Value())
+		b.WriteByte('\n')
+	}
+	b.WriteString(timestamp)
+	b.WriteByte('\n')
+	b.WriteString(nonce)
+	b.WriteByte('\n')
+	b.WriteString(hex.EncodeToString(bodyHash))
+	return b.Bytes()
+}
+
+func Sign(k Key, canonical []byte) string {
+	m := hmac.New(sha256.New, k.Secret)
+	m.Write(canonical)
+	return base64.StdEncoding.EncodeToString(m.Sum(nil))
+}
+
+func Verify(k Key, canonical []byte, signature string) bool {
+	expected, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	m := hmac.New(sha256.New, k.Secret)
+	m.Write(canonical)
+	return hmac.Equal(m.Sum(nil), expected)
+}
+
+func Format(keyId string, signature string) string {
+	return fmt.Sprintf("keyId=%s,signature=%s", keyId, signature)
+}
+
+func Parse(value string) (string, string, error) {
+	var keyId, signature string
+	for _, part := range strings.Split(value, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return "", "", ErrMalformedSignature
+		}
+		switch k {
+		case "keyId":
+			keyId = v
+		case "signature":
+			signature = v
+		}
+	}
+	if keyId == "" || signature == "" {
+		return "", "", ErrMalformedSignature
+	}
+	return keyId, signature, nil
+}
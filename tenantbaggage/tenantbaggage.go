@@ -0,0 +1,89 @@
+package tenantbaggage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Chronicle20/atlas-tenant"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/propagation"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	Header          = "baggage"
+	IdKey           = "atlas.tenant.id"
+	RegionKey       = "atlas.tenant.region"
+	MajorVersionKey = "atlas.tenant.major_version"
+	MinorVersionKey = "atlas.tenant.minor_version"
+)
+
+var ErrNotFound = errors.New("tenant not found in baggage")
+
+// Inject adds the tenant members to the baggage carried by the headers, retaining any other members already present.
+// Baggage which cannot be parsed is kept as it is and the tenant members are appended as a separate value, which
+// receivers read independently of the invalid one.
+func Inject(h http.Header, t tenant.Model) error {
+	b, err := baggage.Parse(strings.Join(h.Values(Header), ","))
+	appended := err != nil
+	if appended {
+		b = baggage.Baggage{}
+	}
+
+	values := map[string]string{
+		IdKey:           t.Id().String(),
+		RegionKey:       t.Region(),
+		MajorVersionKey: strconv.Itoa(int(t.MajorVersion())),
+		MinorVersionKey: strconv.Itoa(int(t.MinorVersion())),
+	}
+	for _, k := range []string{IdKey, RegionKey, MajorVersionKey, MinorVersionKey} {
+		m, err := baggage.NewMemberRaw(k, values[k])
+		if err != nil {
+			return err
+		}
+		b, err = b.SetMember(m)
+		if err != nil {
+			return err
+		}
+	}
+	if appended {
+		h.Add(Header, b.String())
+		return nil
+	}
+	h.Set(Header, b.String())
+	return nil
+}
+
+// Extract reads the tenant from the baggage carried by the headers.
+func Extract(h http.Header) (tenant.Model, error) {
+	b := baggage.FromContext(propagation.Baggage{}.Extract(context.Background(), propagation.HeaderCarrier(h)))
+
+	idStr := b.Member(IdKey).Value()
+	if idStr == "" {
+		return tenant.Model{}, ErrNotFound
+	}
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return tenant.Model{}, fmt.Errorf("%s is invalid: %w", IdKey, err)
+	}
+
+	region := b.Member(RegionKey).Value()
+	if region == "" {
+		return tenant.Model{}, fmt.Errorf("%s is not supplied", RegionKey)
+	}
+
+	majorVersion, err := strconv.Atoi(b.Member(MajorVersionKey).Value())
+	if err != nil {
+		return tenant.Model{}, fmt.Errorf("%s is invalid: %w", MajorVersionKey, err)
+	}
+
+	minorVersion, err := strconv.Atoi(b.Member(MinorVersionKey).Value())
+	if err != nil {
+		return tenant.Model{}, fmt.Errorf("%s is invalid: %w", MinorVersionKey, err)
+	}
+
+	return tenant.Create(id, region, uint16(majorVersion), uint16(minorVersion))
+}